@@ -65,6 +65,9 @@ func (a *App) Run() error {
 	if a.opts.rpcServer != nil {
 		servers = append(servers, a.opts.rpcServer)
 	}
+	if a.opts.muxServer != nil {
+		servers = append(servers, a.opts.muxServer)
+	}
 	// 保证多个server之前的状态同步
 	ctx, cancel := context.WithCancel(context.Background())
 	a.cancel = cancel
@@ -129,10 +132,12 @@ func (a *App) Run() error {
 			return a.Stop()
 		}
 	})
-	if err := eg.Wait(); err != nil {
-		return err
+	err = eg.Wait()
+	// 服务都停止后再关闭客户端连接
+	if a.opts.clientManager != nil {
+		_ = a.opts.clientManager.Close()
 	}
-	return nil
+	return err
 }
 
 // Stop 停止服务
@@ -164,13 +169,22 @@ func (a *App) buildInstance() (*registry.ServiceInstance, error) {
 	}
 
 	// 从rpcServer, restServer去主动获取address信息
+	// TLS开启时endpoint带有isSecure=true，客户端据此选择安全或非安全的地址
 	if a.opts.rpcServer != nil {
-		u := &url.URL{
-			Scheme: "grpc",
-			Host:   a.opts.rpcServer.Address(),
+		u := a.opts.rpcServer.Endpoint()
+		if u == nil {
+			u = &url.URL{
+				Scheme: "grpc",
+				Host:   a.opts.rpcServer.Address(),
+			}
 		}
 		endpoints = append(endpoints, u.String())
 	}
+	if a.opts.muxServer != nil {
+		for _, u := range a.opts.muxServer.Endpoints() {
+			endpoints = append(endpoints, u.String())
+		}
+	}
 
 	// 自动生成endpoints, 自动解析ip地址，自动解析出端口
 	return &registry.ServiceInstance{
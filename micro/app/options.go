@@ -1,6 +1,7 @@
 package app
 
 import (
+	"mymicro/micro/server/muxserver"
 	"mymicro/micro/server/restserver"
 	"mymicro/micro/server/rpcserver"
 	"net/url"
@@ -24,6 +25,9 @@ type options struct {
 
 	rpcServer  *rpcserver.Server
 	restServer *restserver.Server
+	muxServer  *muxserver.Server
+
+	clientManager *rpcserver.ClientManager
 }
 
 func WithRegistrar(registrar registry.Registrar) Option {
@@ -50,6 +54,20 @@ func WithRestServer(server *restserver.Server) Option {
 	}
 }
 
+// WithMuxServer serves the rpc and rest servers on a single port, both grpc:// and http:// endpoints are registered.
+func WithMuxServer(server *muxserver.Server) Option {
+	return func(o *options) {
+		o.muxServer = server
+	}
+}
+
+// WithClientManager closes the client connections of the manager when the app stops.
+func WithClientManager(m *rpcserver.ClientManager) Option {
+	return func(o *options) {
+		o.clientManager = m
+	}
+}
+
 func WithId(id string) Option {
 	return func(o *options) {
 		o.id = id
@@ -0,0 +1,293 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned when the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a breaker.
+type State int32
+
+const (
+	// StateClosed lets all requests through.
+	StateClosed State = iota
+	// StateHalfOpen lets a limited number of probe requests through.
+	StateHalfOpen
+	// StateOpen rejects all requests.
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+type (
+	// Option is breaker option.
+	Option func(o *options)
+
+	options struct {
+		window        time.Duration
+		buckets       int
+		minRequests   int64
+		failureRatio  float64
+		openTimeout   time.Duration
+		halfOpenProbe int64
+		onStateChange func(name string, from, to State)
+	}
+
+	bucket struct {
+		start   int64
+		success int64
+		total   int64
+	}
+
+	// stateChange is a state transition to report once the lock is released.
+	stateChange struct {
+		from, to State
+	}
+
+	// Breaker is a closed, open and half-open circuit breaker.
+	// It trips open when the failure ratio within the rolling window is reached,
+	// turns half-open after the open timeout, and closes after enough successful probes.
+	// The state is updated lazily: an open breaker turns half-open on the first State or Allow call
+	// after the open timeout, there is no timer.
+	Breaker struct {
+		name string
+		opts options
+
+		mu       sync.Mutex
+		state    State
+		openAt   time.Time
+		buckets  []bucket
+		probes   int64
+		probeOks int64
+	}
+)
+
+// WithWindow sets the rolling window and its bucket number.
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(o *options) {
+		o.window = window
+		o.buckets = buckets
+	}
+}
+
+// WithMinRequests sets the min number of requests in the window before tripping.
+func WithMinRequests(n int64) Option {
+	return func(o *options) {
+		o.minRequests = n
+	}
+}
+
+// WithFailureRatio sets the failure ratio which trips the breaker.
+func WithFailureRatio(ratio float64) Option {
+	return func(o *options) {
+		o.failureRatio = ratio
+	}
+}
+
+// WithOpenTimeout sets how long the breaker stays open before half-open.
+func WithOpenTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = d
+	}
+}
+
+// WithHalfOpenProbes sets the number of successful probes required to close the breaker.
+func WithHalfOpenProbes(n int64) Option {
+	return func(o *options) {
+		o.halfOpenProbe = n
+	}
+}
+
+// WithStateChange adds the callback of state changes, the callbacks of several options are called in order.
+// The callback is called after the breaker is unlocked, so it may call the breaker, and the open to half-open
+// change is only reported by the State or Allow call which observes it.
+func WithStateChange(fn func(name string, from, to State)) Option {
+	return func(o *options) {
+		prev := o.onStateChange
+		if prev == nil {
+			o.onStateChange = fn
+			return
+		}
+		o.onStateChange = func(name string, from, to State) {
+			prev(name, from, to)
+			fn(name, from, to)
+		}
+	}
+}
+
+// New creates a breaker.
+func New(name string, opts ...Option) *Breaker {
+	o := options{
+		window:        10 * time.Second,
+		buckets:       10,
+		minRequests:   20,
+		failureRatio:  0.5,
+		openTimeout:   5 * time.Second,
+		halfOpenProbe: 5,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.buckets <= 0 {
+		o.buckets = 1
+	}
+	return &Breaker{
+		name:    name,
+		opts:    o,
+		state:   StateClosed,
+		buckets: make([]bucket, o.buckets),
+	}
+}
+
+// Name returns the name of the breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	var change *stateChange
+	defer func() { b.notify(change) }()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	change = b.refresh(time.Now())
+	return b.state
+}
+
+// Allow returns ErrOpen if the request is rejected.
+// MarkSuccess or MarkFailed must be called when an allowed request is done.
+func (b *Breaker) Allow() error {
+	var change *stateChange
+	defer func() { b.notify(change) }()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	change = b.refresh(time.Now())
+	switch b.state {
+	case StateOpen:
+		return ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.opts.halfOpenProbe {
+			return ErrOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// MarkSuccess records a successful request.
+func (b *Breaker) MarkSuccess() {
+	var change *stateChange
+	defer func() { b.notify(change) }()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if b.state == StateHalfOpen {
+		b.probeOks++
+		if b.probeOks >= b.opts.halfOpenProbe {
+			change = b.setState(StateClosed, now)
+		}
+		return
+	}
+	b.record(now, true)
+}
+
+// MarkFailed records a failed request.
+func (b *Breaker) MarkFailed() {
+	var change *stateChange
+	defer func() { b.notify(change) }()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case StateHalfOpen:
+		change = b.setState(StateOpen, now)
+		return
+	case StateOpen:
+		return
+	}
+	b.record(now, false)
+	success, total := b.summary(now)
+	if total >= b.opts.minRequests && float64(total-success) >= b.opts.failureRatio*float64(total) {
+		change = b.setState(StateOpen, now)
+	}
+}
+
+// refresh turns the breaker half-open after the open timeout.
+func (b *Breaker) refresh(now time.Time) *stateChange {
+	if b.state == StateOpen && now.Sub(b.openAt) >= b.opts.openTimeout {
+		return b.setState(StateHalfOpen, now)
+	}
+	return nil
+}
+
+// setState returns the change to notify after unlocking, nil if the state is not changed.
+func (b *Breaker) setState(state State, now time.Time) *stateChange {
+	from := b.state
+	if from == state {
+		return nil
+	}
+	b.state = state
+	b.probes, b.probeOks = 0, 0
+	switch state {
+	case StateOpen:
+		b.openAt = now
+	case StateClosed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+	return &stateChange{from: from, to: state}
+}
+
+func (b *Breaker) notify(change *stateChange) {
+	if change != nil && b.opts.onStateChange != nil {
+		b.opts.onStateChange(b.name, change.from, change.to)
+	}
+}
+
+func (b *Breaker) bucketSize() int64 {
+	size := int64(b.opts.window) / int64(len(b.buckets))
+	if size <= 0 {
+		size = 1
+	}
+	return size
+}
+
+func (b *Breaker) record(now time.Time, success bool) {
+	size := b.bucketSize()
+	start := now.UnixNano() / size * size
+	bk := &b.buckets[(now.UnixNano()/size)%int64(len(b.buckets))]
+	if bk.start != start {
+		*bk = bucket{start: start}
+	}
+	bk.total++
+	if success {
+		bk.success++
+	}
+}
+
+func (b *Breaker) summary(now time.Time) (success int64, total int64) {
+	oldest := now.UnixNano() - int64(b.opts.window)
+	for _, bk := range b.buckets {
+		if bk.start > oldest {
+			success += bk.success
+			total += bk.total
+		}
+	}
+	return
+}
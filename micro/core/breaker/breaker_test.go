@@ -0,0 +1,98 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	var changes []State
+	b := New("test",
+		WithMinRequests(4),
+		WithFailureRatio(0.5),
+		WithOpenTimeout(20*time.Millisecond),
+		WithHalfOpenProbes(2),
+		WithStateChange(func(_ string, _, to State) { changes = append(changes, to) }),
+	)
+
+	for i := 0; i < 2; i++ {
+		b.MarkSuccess()
+	}
+	b.MarkFailed()
+	if s := b.State(); s != StateClosed {
+		t.Errorf("expect %v, got %v", StateClosed, s)
+	}
+	b.MarkFailed()
+	if s := b.State(); s != StateOpen {
+		t.Errorf("expect %v, got %v", StateOpen, s)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("expect %v, got %v", ErrOpen, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Errorf("expect probe allowed, got %v", err)
+		}
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("expect %v, got %v", ErrOpen, err)
+	}
+	b.MarkSuccess()
+	b.MarkSuccess()
+	if s := b.State(); s != StateClosed {
+		t.Errorf("expect %v, got %v", StateClosed, s)
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("expect %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("expect %v, got %v", want, changes)
+		}
+	}
+}
+
+func TestBreakerHalfOpenFailure(t *testing.T) {
+	b := New("test", WithMinRequests(1), WithOpenTimeout(time.Millisecond))
+	b.MarkFailed()
+	time.Sleep(5 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Errorf("expect probe allowed, got %v", err)
+	}
+	b.MarkFailed()
+	if s := b.State(); s != StateOpen {
+		t.Errorf("expect %v, got %v", StateOpen, s)
+	}
+}
+
+func TestBreakerStateChangeReentrant(t *testing.T) {
+	var b *Breaker
+	var states []State
+	b = New("test", WithMinRequests(1), WithOpenTimeout(time.Minute),
+		WithStateChange(func(_ string, _, _ State) {
+			// 回调在解锁后执行，可以再调用熔断器
+			states = append(states, b.State())
+		}))
+	b.MarkFailed()
+	// 调整打开时间代替等待
+	b.mu.Lock()
+	b.openAt = b.openAt.Add(-time.Minute)
+	b.mu.Unlock()
+	if err := b.Allow(); err != nil {
+		t.Errorf("expect probe allowed, got %v", err)
+	}
+
+	want := []State{StateOpen, StateHalfOpen}
+	if len(states) != len(want) {
+		t.Fatalf("expect %v, got %v", want, states)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("expect %v, got %v", want, states)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package clock
+
+import "time"
+
+// Clock tells the time and waits for durations, it is replaced by a fake clock in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+// Real returns the Clock of the system time.
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
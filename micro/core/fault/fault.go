@@ -0,0 +1,248 @@
+package fault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mymicro/micro/core/clock"
+	"mymicro/pkg/log"
+)
+
+// AnyName is the rule name matching all the methods and routes.
+const AnyName = "*"
+
+// DefaultAdminPath is the path of the admin endpoint changing the rules at runtime.
+const DefaultAdminPath = "/debug/faults"
+
+// ErrDisabled is returned when changing the rules of a disabled injector.
+var ErrDisabled = errors.New("fault injection is disabled")
+
+type (
+	// Conf defines the fault injection, it is disabled unless Enabled is set.
+	Conf struct {
+		Enabled bool   `json:"enabled" mapstructure:"enabled"`
+		Rules   []Rule `json:"rules" mapstructure:"rules"`
+	}
+
+	// Rule defines the fault injected into the requests of a gRPC method or a REST route.
+	// The fault is delaying the request by Delay and then failing it with Code, HTTPStatus or Abort if set.
+	Rule struct {
+		// Name is the full gRPC method or the REST route, e.g. "/user.User/GetUser" or "GET /v1/users/:id",
+		// AnyName matches all the methods and routes.
+		Name string `json:"name" mapstructure:"name"`
+		// Caller restricts the rule to the caller, empty matches all callers.
+		Caller string `json:"caller" mapstructure:"caller"`
+		// Headers restricts the rule to the requests with all the headers or metadata, the keys are case-insensitive.
+		Headers map[string]string `json:"headers" mapstructure:"headers"`
+		// Percentage is the percentage of the matched requests to inject the fault into, in (0, 100].
+		Percentage float64 `json:"percentage" mapstructure:"percentage"`
+		// Delay holds the request before handling or failing it, in nanoseconds in json.
+		Delay time.Duration `json:"delay" mapstructure:"delay"`
+		// Code fails the gRPC requests with the status code if it is not 0 (OK).
+		Code int `json:"code" mapstructure:"code"`
+		// HTTPStatus fails the REST requests with the status if it is not 0.
+		HTTPStatus int `json:"http-status" mapstructure:"http-status"`
+		// Abort aborts the connection of the REST requests, and fails the gRPC requests with Unavailable,
+		// which is what the callers get from a broken connection.
+		Abort bool `json:"abort" mapstructure:"abort"`
+		// Message is the error message, "fault injected" by default.
+		Message string `json:"message" mapstructure:"message"`
+		// Times is the number of requests the rule is injected into, it is removed after that, 0 means no limit.
+		Times int `json:"times" mapstructure:"times"`
+	}
+
+	// Option is injector option.
+	Option func(i *Injector)
+
+	// Injector decides which requests the faults are injected into by the rules.
+	// It is safe to change the rules while serving, e.g. by its admin endpoint.
+	Injector struct {
+		enabled bool
+
+		mu    sync.RWMutex
+		rules []Rule
+		rand  func() float64
+		clock clock.Clock
+	}
+)
+
+// WithClock sets the clock waiting for the delays, e.g. a fake clock in tests, the real clock by default.
+func WithClock(c clock.Clock) Option {
+	return func(i *Injector) {
+		i.clock = c
+	}
+}
+
+// WithRand sets the func returning the random number in [0, 1) deciding the percentage, rand.Float64 by default.
+func WithRand(fn func() float64) Option {
+	return func(i *Injector) {
+		i.rand = fn
+	}
+}
+
+// NewInjector returns an Injector of conf, the rules are ignored if it is not enabled.
+func NewInjector(conf Conf, opts ...Option) (*Injector, error) {
+	i := &Injector{
+		enabled: conf.Enabled,
+		rand:    rand.Float64,
+		clock:   clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if !conf.Enabled {
+		return i, nil
+	}
+	if err := i.SetRules(conf.Rules...); err != nil {
+		return nil, err
+	}
+	log.Warnf("[fault] fault injection is enabled with %d rules", len(conf.Rules))
+	return i, nil
+}
+
+// Enabled reports whether the faults are injected.
+func (i *Injector) Enabled() bool {
+	return i != nil && i.enabled
+}
+
+// Rules returns the current rules.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]Rule(nil), i.rules...)
+}
+
+// SetRules replaces the rules, the earlier rules are preferred when several rules match a request.
+func (i *Injector) SetRules(rules ...Rule) error {
+	if !i.Enabled() {
+		return ErrDisabled
+	}
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return err
+		}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append([]Rule(nil), rules...)
+	return nil
+}
+
+// Fire returns the fault to inject into the request of name from caller,
+// header returns the header or metadata of the request by key.
+func (i *Injector) Fire(name, caller string, header func(key string) string) (Rule, bool) {
+	if !i.Enabled() {
+		return Rule{}, false
+	}
+	// 规则的Times会被修改，使用写锁
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for idx, r := range i.rules {
+		if !r.match(name, caller, header) || i.rand()*100 >= r.Percentage {
+			continue
+		}
+		if r.Times > 0 {
+			if i.rules[idx].Times--; i.rules[idx].Times == 0 {
+				i.rules = append(i.rules[:idx:idx], i.rules[idx+1:]...)
+			}
+		}
+		return r, true
+	}
+	return Rule{}, false
+}
+
+// Wait holds the request by the Delay of the rule on the clock of the injector,
+// it returns the error of ctx if ctx is done first.
+func (i *Injector) Wait(ctx context.Context, r Rule) error {
+	if r.Delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-i.clock.After(r.Delay):
+		return nil
+	}
+}
+
+// Error returns the error message of the rule.
+func (r Rule) Error() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return "fault injected"
+}
+
+func (r Rule) validate() error {
+	if r.Name == "" {
+		return errors.New("fault rule without name")
+	}
+	if r.Percentage <= 0 || r.Percentage > 100 {
+		return fmt.Errorf("fault rule %s: percentage must be in (0, 100], got %v", r.Name, r.Percentage)
+	}
+	if r.Delay < 0 {
+		return fmt.Errorf("fault rule %s: negative delay %v", r.Name, r.Delay)
+	}
+	if r.Times < 0 {
+		return fmt.Errorf("fault rule %s: negative times %d", r.Name, r.Times)
+	}
+	if r.Delay == 0 && r.Code == 0 && r.HTTPStatus == 0 && !r.Abort {
+		return fmt.Errorf("fault rule %s: no fault", r.Name)
+	}
+	return nil
+}
+
+func (r Rule) match(name, caller string, header func(key string) string) bool {
+	if r.Name != AnyName && r.Name != name {
+		return false
+	}
+	if r.Caller != "" && r.Caller != caller {
+		return false
+	}
+	for k, v := range r.Headers {
+		if header == nil || header(strings.ToLower(k)) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP is the admin endpoint of the rules, it responds 404 if the injector is not enabled.
+// GET returns the rules, PUT replaces the rules by the json array in the body, and DELETE removes all the rules.
+// It changes the behavior of the service, so it should only be served on a protected port or behind auth.
+func (i *Injector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !i.Enabled() {
+		http.Error(w, ErrDisabled.Error(), http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var rules []Rule
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := i.SetRules(rules...); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Warnf("[fault] rules replaced by %s, %d rules", r.RemoteAddr, len(rules))
+	case http.MethodDelete:
+		_ = i.SetRules()
+		log.Warnf("[fault] rules removed by %s", r.RemoteAddr)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(i.Rules())
+}
@@ -0,0 +1,144 @@
+package fault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInjectorFire(t *testing.T) {
+	roll := 0.6
+	i, err := NewInjector(Conf{Enabled: true, Rules: []Rule{
+		{Name: "/foo", Caller: "chaos", Percentage: 100, Code: 14},
+		{Name: "/foo", Headers: map[string]string{"X-Fault": "on"}, Percentage: 100, Code: 13},
+		{Name: AnyName, Percentage: 50, Abort: true},
+	}}, WithRand(func() float64 { return roll }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := func(h map[string]string) func(string) string {
+		return func(key string) string { return h[key] }
+	}
+
+	tests := []struct {
+		name   string
+		caller string
+		header map[string]string
+		roll   float64
+		code   int
+		abort  bool
+		fired  bool
+	}{
+		{name: "/foo", caller: "chaos", roll: 0.99, code: 14, fired: true},
+		{name: "/foo", header: map[string]string{"x-fault": "on"}, roll: 0.99, code: 13, fired: true},
+		{name: "/foo", header: map[string]string{"x-fault": "off"}, roll: 0.6},
+		{name: "/bar", roll: 0.6},
+		{name: "/bar", roll: 0.4, abort: true, fired: true},
+	}
+	for _, tt := range tests {
+		roll = tt.roll
+		rule, ok := i.Fire(tt.name, tt.caller, headers(tt.header))
+		if ok != tt.fired || rule.Code != tt.code || rule.Abort != tt.abort {
+			t.Errorf("%s@%s: expect fired %v code %d abort %v, got %v %d %v",
+				tt.name, tt.caller, tt.fired, tt.code, tt.abort, ok, rule.Code, rule.Abort)
+		}
+	}
+}
+
+func TestInjectorDisabled(t *testing.T) {
+	i, err := NewInjector(Conf{Rules: []Rule{{Name: AnyName, Percentage: 100, Abort: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := i.Fire("/foo", "", nil); ok {
+		t.Error("expect no fault when disabled")
+	}
+	if err = i.SetRules(Rule{Name: AnyName, Percentage: 100, Abort: true}); err != ErrDisabled {
+		t.Errorf("expect %v, got %v", ErrDisabled, err)
+	}
+	rec := httptest.NewRecorder()
+	i.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DefaultAdminPath, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expect %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestInjectorAdmin(t *testing.T) {
+	i, err := NewInjector(Conf{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		body   string
+		status int
+		rules  int
+	}{
+		{method: http.MethodPut, body: `[{"name":"/foo","percentage":100,"code":14}]`, status: http.StatusOK, rules: 1},
+		{method: http.MethodPut, body: `[{"name":"/foo","percentage":0,"code":14}]`, status: http.StatusBadRequest, rules: 1},
+		{method: http.MethodPut, body: `[{"name":"/foo","percentage":100}]`, status: http.StatusBadRequest, rules: 1},
+		{method: http.MethodGet, status: http.StatusOK, rules: 1},
+		{method: http.MethodPost, status: http.StatusMethodNotAllowed, rules: 1},
+		{method: http.MethodDelete, status: http.StatusOK, rules: 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		i.ServeHTTP(rec, httptest.NewRequest(tt.method, DefaultAdminPath, strings.NewReader(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expect %d, got %d", tt.method, tt.body, tt.status, rec.Code)
+		}
+		if n := len(i.Rules()); n != tt.rules {
+			t.Errorf("%s %s: expect %d rules, got %d", tt.method, tt.body, tt.rules, n)
+		}
+	}
+}
+
+func TestInjectorTimes(t *testing.T) {
+	i, err := NewInjector(Conf{Enabled: true, Rules: []Rule{
+		{Name: "/foo", Percentage: 100, Code: 14, Times: 2},
+		{Name: AnyName, Percentage: 100, Code: 13},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []int{14, 14, 13} {
+		if rule, ok := i.Fire("/foo", "", nil); !ok || rule.Code != code {
+			t.Errorf("expect code %d, got %v %d", code, ok, rule.Code)
+		}
+	}
+	if n := len(i.Rules()); n != 1 {
+		t.Errorf("expect the rule removed after times, got %d rules", n)
+	}
+}
+
+// manualClock fires the timers when the test sends on fire.
+type manualClock struct {
+	fire chan time.Time
+}
+
+func (c manualClock) Now() time.Time { return time.Now() }
+
+func (c manualClock) After(time.Duration) <-chan time.Time { return c.fire }
+
+func TestInjectorWait(t *testing.T) {
+	clk := manualClock{fire: make(chan time.Time, 1)}
+	i, err := NewInjector(Conf{Enabled: true}, WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := Rule{Name: AnyName, Percentage: 100, Delay: time.Hour}
+
+	clk.fire <- time.Now()
+	if err = i.Wait(context.Background(), rule); err != nil {
+		t.Errorf("expect delay by the clock, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = i.Wait(ctx, rule); err != context.Canceled {
+		t.Errorf("expect %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,45 @@
+package load
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	cpuSampleInterval = 500 * time.Millisecond
+	// cpuDecay is the decay of the exponential moving average of cpu usage.
+	cpuDecay = 0.95
+)
+
+var (
+	cpuUsage int64
+	cpuOnce  sync.Once
+)
+
+// CpuUsage returns the moving average cpu usage of the process in per-mille, 1000 means all cores are busy.
+// The sampling starts on the first call.
+func CpuUsage() int64 {
+	cpuOnce.Do(startCpuSampling)
+	return atomic.LoadInt64(&cpuUsage)
+}
+
+func startCpuSampling() {
+	go func() {
+		ticker := time.NewTicker(cpuSampleInterval)
+		defer ticker.Stop()
+		lastCpu, lastTime := processCpuTime(), time.Now()
+		for now := range ticker.C {
+			cur := processCpuTime()
+			elapsed := now.Sub(lastTime)
+			if elapsed <= 0 {
+				continue
+			}
+			usage := float64(cur-lastCpu) / float64(elapsed) / float64(runtime.NumCPU()) * 1000
+			prev := atomic.LoadInt64(&cpuUsage)
+			atomic.StoreInt64(&cpuUsage, int64(float64(prev)*cpuDecay+usage*(1-cpuDecay)))
+			lastCpu, lastTime = cur, now
+		}
+	}()
+}
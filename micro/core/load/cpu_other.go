@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package load
+
+import "time"
+
+// processCpuTime is not supported on this platform, the cpu usage is always 0.
+func processCpuTime() time.Duration {
+	return 0
+}
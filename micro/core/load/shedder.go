@@ -0,0 +1,186 @@
+package load
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// coolOffDuration keeps shedding for a while after the cpu usage drops below the threshold,
+	// to avoid shedding and passing alternately.
+	coolOffDuration = time.Second
+)
+
+// ErrServiceOverloaded is returned by Shedder.Allow when the service is overloaded.
+var ErrServiceOverloaded = errors.New("service overloaded")
+
+type (
+	// DoneFunc must be called when an allowed request is done.
+	DoneFunc func()
+
+	// Shedder is the interface that decides whether a request should be shed.
+	Shedder interface {
+		Allow() (DoneFunc, error)
+	}
+
+	// ShedderOption is adaptive shedder option.
+	ShedderOption func(o *shedderOptions)
+
+	shedderOptions struct {
+		window       time.Duration
+		buckets      int
+		cpuThreshold int64
+		cpuUsage     func() int64
+	}
+
+	shedderBucket struct {
+		idx     int64
+		pass    int64
+		rtSum   time.Duration
+		rtCount int64
+	}
+
+	// adaptiveShedder is modelled on BBR, it estimates the max concurrency by the max pass
+	// per bucket multiplied by the min rt in the window, and sheds requests
+	// when the cpu usage is over the threshold and the inflight is over the max concurrency.
+	adaptiveShedder struct {
+		opts       shedderOptions
+		bucketSize time.Duration
+		inflight   int64
+		dropTime   int64
+
+		mu      sync.Mutex
+		buckets []shedderBucket
+	}
+)
+
+// WithShedderWindow sets the statistic window and its bucket number, 5s and 50 by default.
+func WithShedderWindow(window time.Duration, buckets int) ShedderOption {
+	return func(o *shedderOptions) {
+		o.window = window
+		o.buckets = buckets
+	}
+}
+
+// WithCpuThreshold sets the cpu usage in per-mille over which requests may be shed, 900 by default.
+func WithCpuThreshold(threshold int64) ShedderOption {
+	return func(o *shedderOptions) {
+		o.cpuThreshold = threshold
+	}
+}
+
+// WithCpuUsageFunc sets the func of cpu usage in per-mille, CpuUsage by default.
+func WithCpuUsageFunc(fn func() int64) ShedderOption {
+	return func(o *shedderOptions) {
+		o.cpuUsage = fn
+	}
+}
+
+// NewAdaptiveShedder returns an adaptive shedder.
+func NewAdaptiveShedder(opts ...ShedderOption) Shedder {
+	o := shedderOptions{
+		window:       5 * time.Second,
+		buckets:      50,
+		cpuThreshold: 900,
+		cpuUsage:     CpuUsage,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.buckets <= 0 {
+		o.buckets = 1
+	}
+	bucketSize := o.window / time.Duration(o.buckets)
+	if bucketSize <= 0 {
+		bucketSize = time.Millisecond
+	}
+	return &adaptiveShedder{
+		opts:       o,
+		bucketSize: bucketSize,
+		buckets:    make([]shedderBucket, o.buckets),
+	}
+}
+
+// Allow returns ErrServiceOverloaded if the request should be shed.
+func (s *adaptiveShedder) Allow() (DoneFunc, error) {
+	if s.shouldDrop() {
+		return nil, ErrServiceOverloaded
+	}
+	atomic.AddInt64(&s.inflight, 1)
+	start := time.Now()
+	return func() {
+		atomic.AddInt64(&s.inflight, -1)
+		s.add(time.Now(), time.Since(start))
+	}, nil
+}
+
+func (s *adaptiveShedder) shouldDrop() bool {
+	now := time.Now()
+	if s.opts.cpuUsage() < s.opts.cpuThreshold {
+		dropTime := atomic.LoadInt64(&s.dropTime)
+		if dropTime == 0 {
+			return false
+		}
+		if now.Sub(time.Unix(0, dropTime)) > coolOffDuration {
+			atomic.StoreInt64(&s.dropTime, 0)
+			return false
+		}
+		return s.overloaded(now)
+	}
+	if !s.overloaded(now) {
+		return false
+	}
+	atomic.CompareAndSwapInt64(&s.dropTime, 0, now.UnixNano())
+	return true
+}
+
+func (s *adaptiveShedder) overloaded(now time.Time) bool {
+	inflight := atomic.LoadInt64(&s.inflight)
+	maxFlight := s.maxFlight(now)
+	return maxFlight > 0 && inflight > 1 && inflight > maxFlight
+}
+
+// maxFlight returns the estimated max concurrency, 0 if there are no statistics yet.
+func (s *adaptiveShedder) maxFlight(now time.Time) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur := now.UnixNano() / int64(s.bucketSize)
+	var (
+		maxPass int64
+		minRt   = math.MaxFloat64
+	)
+	for _, b := range s.buckets {
+		// the current bucket is incomplete
+		if b.idx == cur || cur-b.idx >= int64(len(s.buckets)) {
+			continue
+		}
+		if b.pass > maxPass {
+			maxPass = b.pass
+		}
+		if b.rtCount > 0 {
+			if rt := float64(b.rtSum) / float64(b.rtCount); rt < minRt {
+				minRt = rt
+			}
+		}
+	}
+	if maxPass == 0 || minRt == math.MaxFloat64 {
+		return 0
+	}
+	return int64(math.Ceil(float64(maxPass) * minRt / float64(s.bucketSize)))
+}
+
+func (s *adaptiveShedder) add(now time.Time, rt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := now.UnixNano() / int64(s.bucketSize)
+	b := &s.buckets[idx%int64(len(s.buckets))]
+	if b.idx != idx {
+		*b = shedderBucket{idx: idx}
+	}
+	b.pass++
+	b.rtSum += rt
+	b.rtCount++
+}
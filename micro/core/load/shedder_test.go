@@ -0,0 +1,51 @@
+package load
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveShedder(t *testing.T) {
+	var cpu int64
+	s := NewAdaptiveShedder(
+		WithShedderWindow(time.Second, 10),
+		WithCpuUsageFunc(func() int64 { return atomic.LoadInt64(&cpu) }),
+	).(*adaptiveShedder)
+
+	// 10 passes with 100ms rt in a previous 100ms bucket make a max concurrency of 10
+	prev := time.Now().Add(-s.bucketSize)
+	for i := 0; i < 10; i++ {
+		s.add(prev, 100*time.Millisecond)
+	}
+	if n := s.maxFlight(time.Now()); n != 10 {
+		t.Fatalf("expect 10, got %d", n)
+	}
+
+	var dones []DoneFunc
+	for i := 0; i < 11; i++ {
+		done, err := s.Allow()
+		if err != nil {
+			t.Fatalf("expect allowed under low cpu, got %v", err)
+		}
+		dones = append(dones, done)
+	}
+
+	atomic.StoreInt64(&cpu, 950)
+	if _, err := s.Allow(); err != ErrServiceOverloaded {
+		t.Errorf("expect %v, got %v", ErrServiceOverloaded, err)
+	}
+
+	// keep shedding in the cool off duration
+	atomic.StoreInt64(&cpu, 100)
+	if _, err := s.Allow(); err != ErrServiceOverloaded {
+		t.Errorf("expect %v, got %v", ErrServiceOverloaded, err)
+	}
+
+	for _, done := range dones {
+		done()
+	}
+	if _, err := s.Allow(); err != nil {
+		t.Errorf("expect allowed, got %v", err)
+	}
+}
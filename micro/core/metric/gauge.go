@@ -16,6 +16,8 @@ type (
 		Inc(labels ...string)
 		// Add adds v to labels.
 		Add(v float64, labels ...string)
+		// Delete deletes the gauge with labels.
+		Delete(labels ...string) bool
 	}
 
 	promGaugeVec struct {
@@ -55,3 +57,7 @@ func (gv *promGaugeVec) Add(v float64, labels ...string) {
 func (gv *promGaugeVec) Set(v float64, labels ...string) {
 	gv.gauge.WithLabelValues(labels...).Set(v)
 }
+
+func (gv *promGaugeVec) Delete(labels ...string) bool {
+	return gv.gauge.DeleteLabelValues(labels...)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// localIdleTimeout is the idle time after which the bucket of a key is removed.
+const localIdleTimeout = 10 * time.Minute
+
+type (
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	localLimiter struct {
+		mu        sync.Mutex
+		buckets   map[string]*tokenBucket
+		lastSweep time.Time
+	}
+)
+
+// NewLocalLimiter returns an in-process token bucket limiter.
+func NewLocalLimiter() Limiter {
+	return &localLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *localLimiter) Allow(_ context.Context, key string, rate float64, burst int) (bool, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false, nil
+	}
+	b.tokens--
+	return true, nil
+}
+
+// sweep removes the idle buckets, their tokens must be full.
+func (l *localLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < localIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= localIdleTimeout {
+			delete(l.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRuleLimiter(t *testing.T) {
+	l := NewRuleLimiter(NewLocalLimiter(),
+		Rule{Name: "/foo", Rate: 1, Burst: 2},
+		Rule{Name: "/foo", Caller: "vip", Rate: 100, Burst: 5},
+		Rule{Name: AnyName, Rate: 1, Burst: 1, PerCaller: true},
+	)
+
+	tests := []struct {
+		name    string
+		caller  string
+		allowed int
+	}{
+		{name: "/foo", caller: "a", allowed: 2},
+		// shared with caller a
+		{name: "/foo", caller: "b", allowed: 0},
+		{name: "/foo", caller: "vip", allowed: 5},
+		{name: "/bar", caller: "a", allowed: 1},
+		// per caller
+		{name: "/bar", caller: "b", allowed: 1},
+	}
+	for _, tt := range tests {
+		var allowed int
+		for i := 0; i < 10; i++ {
+			if l.Allow(context.Background(), tt.name, tt.caller) == nil {
+				allowed++
+			}
+		}
+		if allowed != tt.allowed {
+			t.Errorf("%s@%s: expect %d allowed, got %d", tt.name, tt.caller, tt.allowed, allowed)
+		}
+	}
+}
+
+func TestLocalLimiterRefill(t *testing.T) {
+	l := NewLocalLimiter()
+	ctx := context.Background()
+	if ok, _ := l.Allow(ctx, "k", 100, 1); !ok {
+		t.Fatal("expect allowed")
+	}
+	if ok, _ := l.Allow(ctx, "k", 100, 1); ok {
+		t.Fatal("expect limited")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := l.Allow(ctx, "k", 100, 1); !ok {
+		t.Error("expect allowed after refill")
+	}
+}
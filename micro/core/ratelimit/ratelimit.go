@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+
+	"mymicro/pkg/log"
+)
+
+// ErrLimited is returned when the request exceeds the rate limit.
+var ErrLimited = errors.New("rate limit exceeded")
+
+// AnyName is the rule name matching all the methods and routes without a rule of their own.
+const AnyName = "*"
+
+type (
+	// Limiter is the interface of rate limiters.
+	Limiter interface {
+		// Allow reports whether a request of key is allowed,
+		// rate is the number of requests allowed per second and burst is the max number allowed at once.
+		Allow(ctx context.Context, key string, rate float64, burst int) (bool, error)
+	}
+
+	// Rule defines the quota of a gRPC method or a REST route.
+	Rule struct {
+		// Name is the full gRPC method or the REST route, e.g. "/user.User/GetUser" or "GET /v1/users/:id",
+		// AnyName matches all the methods and routes without a rule of their own.
+		Name string `json:"name" mapstructure:"name"`
+		// Caller restricts the rule to the caller, empty matches all callers.
+		Caller string `json:"caller" mapstructure:"caller"`
+		// Rate is the number of requests allowed per second.
+		Rate float64 `json:"rate" mapstructure:"rate"`
+		// Burst is the max number of requests allowed at once, the ceil of Rate if not set.
+		Burst int `json:"burst" mapstructure:"burst"`
+		// PerCaller applies the quota to each caller separately instead of sharing it among callers.
+		PerCaller bool `json:"per-caller" mapstructure:"per-caller"`
+	}
+
+	ruleKey struct {
+		name   string
+		caller string
+	}
+
+	// RuleLimiter enforces the rules with a limiter.
+	RuleLimiter struct {
+		limiter Limiter
+		rules   map[ruleKey]Rule
+	}
+)
+
+// NewRuleLimiter returns a RuleLimiter.
+func NewRuleLimiter(limiter Limiter, rules ...Rule) *RuleLimiter {
+	m := make(map[ruleKey]Rule, len(rules))
+	for _, r := range rules {
+		if r.Name == "" || r.Rate <= 0 {
+			continue
+		}
+		if r.Burst <= 0 {
+			r.Burst = int(r.Rate)
+			if float64(r.Burst) < r.Rate {
+				r.Burst++
+			}
+		}
+		m[ruleKey{name: r.Name, caller: r.Caller}] = r
+	}
+	return &RuleLimiter{limiter: limiter, rules: m}
+}
+
+// Allow returns ErrLimited if the request of name from caller exceeds the matched rule.
+// The rules of the name are preferred to AnyName, and the rules of the caller are preferred to the shared ones.
+// Requests are allowed if the limiter fails, e.g. the redis is down.
+func (l *RuleLimiter) Allow(ctx context.Context, name, caller string) error {
+	rule, ok := l.match(name, caller)
+	if !ok {
+		return nil
+	}
+	key := name
+	if rule.PerCaller || rule.Caller != "" {
+		key += "@" + caller
+	}
+	allowed, err := l.limiter.Allow(ctx, key, rule.Rate, rule.Burst)
+	if err != nil {
+		log.Warnf("[ratelimit] limiter failed, allow request %s: %s", key, err.Error())
+		return nil
+	}
+	if !allowed {
+		return ErrLimited
+	}
+	return nil
+}
+
+func (l *RuleLimiter) match(name, caller string) (Rule, bool) {
+	for _, k := range []ruleKey{
+		{name: name, caller: caller},
+		{name: name},
+		{name: AnyName, caller: caller},
+		{name: AnyName},
+	} {
+		if r, ok := l.rules[k]; ok {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
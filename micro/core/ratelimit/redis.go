@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	redis "github.com/redis/go-redis/v9"
+
+	"mymicro/pkg/storage"
+)
+
+// gcraScript implements the generic cell rate algorithm, the theoretical arrival time is kept in microseconds.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local interval = 1000000 / rate
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call("GET", key))
+if not tat or tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+if new_tat - interval * burst > now then
+	return 0
+end
+local ttl = math.ceil((new_tat - now) / 1000)
+redis.call("SET", key, string.format("%.0f", new_tat), "PX", ttl)
+return 1
+`)
+
+type redisLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisLimiter returns a distributed limiter on the redis of the storage,
+// the quota of each key is shared by all the instances.
+func NewRedisLimiter(rc *storage.RedisCluster) Limiter {
+	return &redisLimiter{
+		client: rc.GetClient(),
+		prefix: rc.GetKeyPrefix() + "ratelimit:",
+	}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, rate float64, burst int) (bool, error) {
+	if !storage.Connected() {
+		return false, storage.ErrRedisIsDown
+	}
+	res, err := gcraScript.Run(ctx, l.client, []string{l.prefix + key},
+		strconv.FormatFloat(rate, 'f', -1, 64), burst).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
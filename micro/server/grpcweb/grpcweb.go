@@ -0,0 +1,236 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	mws "mymicro/micro/server/restserver/middlewares"
+)
+
+const (
+	contentTypeGRPC    = "application/grpc"
+	contentTypeWeb     = "application/grpc-web"
+	contentTypeWebText = "application/grpc-web-text"
+
+	// trailerFlag marks the trailer frame in the response body.
+	trailerFlag = 0x80
+)
+
+var (
+	// grpcWebHeaders are the request headers of grpc-web clients allowed by CORS.
+	grpcWebHeaders = []string{"X-Grpc-Web", "X-User-Agent", "Grpc-Timeout", "Content-Type", "Authorization", "x-token"}
+	// grpcWebExposeHeaders are the response headers read by grpc-web clients.
+	grpcWebExposeHeaders = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+)
+
+type (
+	// Option is gRPC-Web handler option.
+	Option func(h *Handler)
+
+	// Handler serves the gRPC-Web requests of browser clients by a grpc.Server,
+	// both binary (application/grpc-web) and text (application/grpc-web-text) framing are supported.
+	// The requests go through the server interceptors, so tracing and metrics apply like any other RPC.
+	Handler struct {
+		server *grpc.Server
+		cors   mws.CorsConf
+	}
+)
+
+// WithCors sets the CORS configuration, the headers required by gRPC-Web are always allowed and exposed.
+func WithCors(conf mws.CorsConf) Option {
+	return func(h *Handler) {
+		h.cors = conf
+	}
+}
+
+// NewHandler returns a gRPC-Web handler of the server, CORS is configured by mws.DefaultCorsConf by default.
+func NewHandler(server *grpc.Server, opts ...Option) *Handler {
+	h := &Handler{
+		server: server,
+		cors:   mws.DefaultCorsConf(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.cors.AllowHeaders = append(append([]string(nil), h.cors.AllowHeaders...), grpcWebHeaders...)
+	h.cors.ExposeHeaders = append(append([]string(nil), h.cors.ExposeHeaders...), grpcWebExposeHeaders...)
+	return h
+}
+
+// IsGRPCWebRequest reports whether r is a gRPC-Web request.
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeWeb)
+}
+
+// IsPreflightRequest reports whether r is the CORS preflight request of a gRPC-Web request.
+func IsPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		strings.Contains(strings.ToLower(r.Header.Get("Access-Control-Request-Headers")), "x-grpc-web")
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.cors.SetHeaders(w.Header(), r.Header.Get("Origin"))
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !IsGRPCWebRequest(r) {
+		http.Error(w, "invalid gRPC-Web request", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, contentTypeWebText)
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header.Set("Content-Type", contentTypeGRPC+subtype(contentType))
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	if text {
+		body, err := decodeText(r.Body)
+		if err != nil {
+			http.Error(w, "invalid base64 body", http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	rw := newResponseWriter(w, text)
+	h.server.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// subtype returns the codec suffix of the content type, e.g. +proto.
+func subtype(contentType string) string {
+	contentType = strings.TrimPrefix(strings.TrimPrefix(contentType, contentTypeWebText), contentTypeWeb)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if contentType == "" {
+		return "+proto"
+	}
+	return contentType
+}
+
+// decodeText decodes the base64 body, which may be the concatenation of padded chunks.
+func decodeText(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	out := make([]byte, 0, base64.StdEncoding.DecodedLen(len(b)))
+	buf := make([]byte, 3)
+	for len(b) > 0 {
+		n := 4
+		if len(b) < n {
+			n = len(b)
+		}
+		m, err := base64.StdEncoding.Decode(buf, b[:n])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, buf[:m]...)
+		b = b[n:]
+	}
+	return out, nil
+}
+
+// responseWriter converts the gRPC response into gRPC-Web,
+// the trailers are written as the trailer frame of the body.
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	text        bool
+	wroteHeader bool
+}
+
+var _ http.Flusher = (*responseWriter)(nil)
+
+func newResponseWriter(w http.ResponseWriter, text bool) *responseWriter {
+	return &responseWriter{w: w, header: make(http.Header), text: text}
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	h := rw.w.Header()
+	for k, vs := range rw.header {
+		if k == "Trailer" || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		h[k] = vs
+	}
+	contentType := contentTypeWeb
+	if rw.text {
+		contentType = contentTypeWebText
+	}
+	h.Set("Content-Type", contentType+subtype(strings.TrimPrefix(rw.header.Get("Content-Type"), contentTypeGRPC)))
+	h.Del("Content-Length")
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.text {
+		if _, err := rw.w.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return rw.w.Write(b)
+}
+
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailers declared by the Trailer header or set with http.TrailerPrefix.
+func (rw *responseWriter) finish() {
+	trailers := make(http.Header)
+	for _, k := range rw.header.Values("Trailer") {
+		if vs := rw.header.Values(k); len(vs) > 0 {
+			trailers[strings.ToLower(k)] = vs
+		}
+	}
+	for k, vs := range rw.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			trailers[strings.ToLower(strings.TrimPrefix(k, http.TrailerPrefix))] = vs
+		}
+	}
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	var buf bytes.Buffer
+	for k, vs := range trailers {
+		for _, v := range vs {
+			buf.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	frame = append(frame, buf.Bytes()...)
+	_, _ = rw.Write(frame)
+	rw.Flush()
+}
@@ -0,0 +1,130 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// parseFrames returns the messages and trailers of the gRPC-Web response body.
+func parseFrames(t *testing.T, body []byte) ([][]byte, string) {
+	var (
+		msgs     [][]byte
+		trailers string
+	)
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("invalid frame %v", body)
+		}
+		n := binary.BigEndian.Uint32(body[1:5])
+		data := body[5 : 5+n]
+		if body[0]&trailerFlag != 0 {
+			trailers = string(data)
+		} else {
+			msgs = append(msgs, data)
+		}
+		body = body[5+n:]
+	}
+	return msgs, trailers
+}
+
+func frame(t *testing.T, m proto.Message) []byte {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(f[1:], uint32(len(b)))
+	return append(f, b...)
+}
+
+func TestHandler(t *testing.T) {
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	ts := httptest.NewServer(NewHandler(srv))
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		status      string
+		msgs        int
+	}{
+		{name: "binary", method: "/grpc.health.v1.Health/Check", contentType: contentTypeWeb + "+proto",
+			status: "grpc-status: 0", msgs: 1},
+		{name: "text", method: "/grpc.health.v1.Health/Check", contentType: contentTypeWebText,
+			status: "grpc-status: 0", msgs: 1},
+		{name: "unknown", method: "/grpc.health.v1.Health/Unknown", contentType: contentTypeWeb,
+			status: "grpc-status: 12"},
+	}
+	for _, tt := range tests {
+		body := frame(t, &grpc_health_v1.HealthCheckRequest{})
+		text := strings.HasPrefix(tt.contentType, contentTypeWebText)
+		if text {
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+		}
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+tt.method, bytes.NewReader(body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("X-Grpc-Web", "1")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if !strings.HasPrefix(res.Header.Get("Content-Type"), strings.Split(tt.contentType, "+")[0]) {
+			t.Errorf("%s: expect content type %s, got %s", tt.name, tt.contentType, res.Header.Get("Content-Type"))
+		}
+		if text {
+			if b, err = decodeText(bytes.NewReader(b)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		msgs, trailers := parseFrames(t, b)
+		if !strings.Contains(trailers, tt.status) {
+			t.Errorf("%s: expect %s, got %q", tt.name, tt.status, trailers)
+		}
+		if len(msgs) != tt.msgs {
+			t.Fatalf("%s: expect %d messages, got %d", tt.name, tt.msgs, len(msgs))
+		}
+		if tt.msgs > 0 {
+			var resp grpc_health_v1.HealthCheckResponse
+			if err = proto.Unmarshal(msgs[0], &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+				t.Errorf("%s: expect SERVING, got %v", tt.name, resp.GetStatus())
+			}
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodOptions, ts.URL+"/grpc.health.v1.Health/Check", nil)
+	req.Header.Set("Origin", "http://admin.example.com")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	if !IsPreflightRequest(req) {
+		t.Error("expect preflight request")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("expect %d, got %d", http.StatusNoContent, res.StatusCode)
+	}
+	if !strings.Contains(res.Header.Get("Access-Control-Allow-Headers"), "X-Grpc-Web") ||
+		!strings.Contains(res.Header.Get("Access-Control-Expose-Headers"), "Grpc-Status") {
+		t.Errorf("unexpected CORS headers %v", res.Header)
+	}
+}
@@ -0,0 +1,173 @@
+package muxserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"mymicro/micro/server/grpcweb"
+	"mymicro/micro/server/restserver"
+	"mymicro/micro/server/rpcserver"
+	"mymicro/pkg/host"
+	"mymicro/pkg/log"
+)
+
+type ServerOption func(o *Server)
+
+// Server serves the rpcserver and restserver on a single listener,
+// HTTP/2 requests with content-type application/grpc go to the grpc.Server and the others go to the gin engine.
+// Plaintext HTTP/2 is supported by h2c.
+type Server struct {
+	address    string
+	lis        net.Listener
+	rpcServer  *rpcserver.Server
+	restServer *restserver.Server
+	grpcWeb    bool
+	webOpts    []grpcweb.Option
+
+	server    *http.Server
+	endpoints []*url.URL
+}
+
+func NewServer(opts ...ServerOption) *Server {
+	srv := Server{
+		address: ":0",
+	}
+	for _, opt := range opts {
+		opt(&srv)
+	}
+	if err := srv.listenAndEndpoint(); err != nil {
+		log.Errorf("[mux] Failed to listen on %s: %v", srv.address, err)
+		return nil
+	}
+	return &srv
+}
+
+// 提取ip和端口，grpc和http的endpoint使用同一个地址
+func (s *Server) listenAndEndpoint() error {
+	if s.lis == nil {
+		lis, err := net.Listen("tcp", s.address)
+		if err != nil {
+			return err
+		}
+		s.lis = lis
+	}
+	addr, err := host.Extract(s.address, s.lis)
+	if err != nil {
+		_ = s.lis.Close()
+		return err
+	}
+	if s.rpcServer != nil {
+		s.endpoints = append(s.endpoints, &url.URL{Scheme: "grpc", Host: addr})
+	}
+	if s.restServer != nil {
+		s.endpoints = append(s.endpoints, &url.URL{Scheme: "http", Host: addr})
+	}
+	return nil
+}
+
+func (s *Server) Address() string { return s.address }
+
+// Endpoints returns the grpc:// and http:// endpoints of the shared address.
+func (s *Server) Endpoints() []*url.URL { return s.endpoints }
+
+// Start serves the servers in plaintext, it fails if the rpcserver is configured with TLS,
+// which would be served without TLS otherwise.
+func (s *Server) Start(ctx context.Context) error {
+	if s.rpcServer != nil && s.rpcServer.TLSEnabled() {
+		return errors.New("mux server does not support the rpcserver with TLS, serve it on its own listener")
+	}
+	var restHandler http.Handler = http.NotFoundHandler()
+	if s.restServer != nil {
+		h, err := s.restServer.Handler()
+		if err != nil {
+			return err
+		}
+		restHandler = h
+	}
+	var webHandler http.Handler
+	if s.rpcServer != nil {
+		s.rpcServer.Attach(ctx)
+		if s.grpcWeb {
+			webHandler = grpcweb.NewHandler(s.rpcServer.Server, s.webOpts...)
+		}
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if webHandler != nil && (grpcweb.IsGRPCWebRequest(r) || grpcweb.IsPreflightRequest(r)) {
+			webHandler.ServeHTTP(w, r)
+			return
+		}
+		if s.rpcServer != nil && r.ProtoMajor == 2 &&
+			strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			s.rpcServer.ServeHTTP(w, r)
+			return
+		}
+		restHandler.ServeHTTP(w, r)
+	})
+	h2s := &http2.Server{}
+	s.server = &http.Server{
+		Handler: h2c.NewHandler(handler, h2s),
+	}
+	// Shutdown时通过h2s向h2c连接发送GOAWAY
+	if err := http2.ConfigureServer(s.server, h2s); err != nil {
+		return err
+	}
+	log.Infof("[mux] server listening on: %s", s.lis.Addr().String())
+	if err := s.server.Serve(s.lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	var err error
+	if s.server != nil {
+		err = s.server.Shutdown(ctx)
+	}
+	if s.rpcServer != nil {
+		s.rpcServer.Detach()
+	}
+	log.Info("[mux] server stopped")
+	return err
+}
+
+func WithAddress(address string) ServerOption {
+	return func(s *Server) {
+		s.address = address
+	}
+}
+
+func WithLis(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.lis = lis
+	}
+}
+
+// WithRPCServer serves the grpc requests by the rpcserver, its own address is not used.
+func WithRPCServer(server *rpcserver.Server) ServerOption {
+	return func(s *Server) {
+		s.rpcServer = server
+	}
+}
+
+// WithGRPCWeb serves the gRPC-Web requests of browsers by the rpcserver.
+func WithGRPCWeb(opts ...grpcweb.Option) ServerOption {
+	return func(s *Server) {
+		s.grpcWeb = true
+		s.webOpts = opts
+	}
+}
+
+// WithRestServer serves the other requests by the restserver, its own port is not used.
+func WithRestServer(server *restserver.Server) ServerOption {
+	return func(s *Server) {
+		s.restServer = server
+	}
+}
@@ -0,0 +1,68 @@
+package muxserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"mymicro/micro/server/restserver"
+	"mymicro/micro/server/rpcserver"
+)
+
+func TestServer(t *testing.T) {
+	rpcSrv := rpcserver.NewServer(rpcserver.WithAddress("127.0.0.1:0"))
+	restSrv := restserver.NewServer(restserver.WithMode(gin.TestMode), restserver.WithEnableProfiling(false))
+	restSrv.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	srv := NewServer(WithAddress("127.0.0.1:0"), WithRPCServer(rpcSrv), WithRestServer(restSrv))
+	if srv == nil {
+		t.Fatal("expect server, got nil")
+	}
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	defer srv.Stop(context.Background())
+
+	addr := srv.lis.Addr().String()
+	var schemes []string
+	for _, u := range srv.Endpoints() {
+		schemes = append(schemes, u.Scheme)
+		if u.Host != addr {
+			t.Errorf("expect host %s, got %s", addr, u.Host)
+		}
+	}
+	if len(schemes) != 2 || schemes[0] != "grpc" || schemes[1] != "http" {
+		t.Errorf("expect [grpc http], got %v", schemes)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := rpcserver.DailInsecure(ctx, rpcserver.WithEndpoint(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("expect SERVING, got %v", resp.GetStatus())
+	}
+
+	res, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Errorf("expect 200 pong, got %d %s", res.StatusCode, body)
+	}
+}
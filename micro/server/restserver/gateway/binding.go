@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// setField sets the values of the field path of msg, e.g. book.id,
+// the fields are matched by the proto name or json name.
+// hasField reports whether the field path, e.g. "book.id", is found in the message.
+func hasField(md protoreflect.MessageDescriptor, path string) bool {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := findField(md, name)
+		if fd == nil {
+			return false
+		}
+		if i == len(names)-1 {
+			return true
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return false
+		}
+		md = fd.Message()
+	}
+	return false
+}
+
+func setField(msg protoreflect.Message, path string, values []string) error {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		fd := findField(msg.Descriptor(), name)
+		if fd == nil {
+			return fmt.Errorf("field %s not found in %s", path, msg.Descriptor().FullName())
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("field %s of %s is not a message", name, path)
+		}
+		msg = msg.Mutable(fd).Message()
+	}
+	fd := findField(msg.Descriptor(), names[len(names)-1])
+	if fd == nil {
+		return fmt.Errorf("field %s not found in %s", path, msg.Descriptor().FullName())
+	}
+	if fd.IsMap() {
+		return fmt.Errorf("map field %s is not supported in path or query", path)
+	}
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, s := range values {
+			v, err := parseValue(fd, s, list.NewElement)
+			if err != nil {
+				return fmt.Errorf("invalid value %q of field %s: %w", s, path, err)
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	v, err := parseValue(fd, values[len(values)-1], func() protoreflect.Value { return msg.NewField(fd) })
+	if err != nil {
+		return fmt.Errorf("invalid value %q of field %s: %w", values[len(values)-1], path, err)
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return fields.ByJSONName(name)
+}
+
+// parseValue parses the string value of the field,
+// message fields such as google.protobuf.Timestamp are parsed as json strings.
+func parseValue(fd protoreflect.FieldDescriptor, s string,
+	newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		b, err := base64.URLEncoding.DecodeString(s)
+		if err != nil {
+			if b, err = base64.StdEncoding.DecodeString(s); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return protoreflect.ValueOfBytes(b), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		v := newMessage()
+		m := v.Message().Interface()
+		// 先按json字符串解析(Timestamp, Duration, FieldMask等)，再按原始json解析(数值和布尔的包装类型)
+		if err := protojson.Unmarshal([]byte(strconv.Quote(s)), m); err != nil {
+			if err = protojson.Unmarshal([]byte(s), m); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return v, nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", fd.Kind())
+}
@@ -0,0 +1,364 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"mymicro/micro/server/restserver/middlewares"
+	"mymicro/micro/server/rpcserver/rpcerrors"
+	"mymicro/pkg/common/core"
+	"mymicro/pkg/errors"
+	"mymicro/pkg/log"
+)
+
+const (
+	// MetadataHeaderPrefix is the prefix of the http headers forwarded as gRPC metadata without the prefix.
+	MetadataHeaderPrefix = "Grpc-Metadata-"
+
+	// DefaultMaxBodySize is the max size of the request bodies, the same as the default max message size of gRPC.
+	DefaultMaxBodySize = 4 << 20
+
+	// unknownCode is the business code of the errors without code of pkg/errors,
+	// it is reserved by pkg/errors and never registered, so the http status carried by the error is kept.
+	unknownCode = 0
+)
+
+var (
+	errBodyTooLarge = errors.New("request body is larger than the limit")
+
+	// identityMetadata are the metadata keys identifying the callers, they are not forwarded from the clients.
+	identityMetadata = map[string]struct{}{
+		middlewares.CallerHeader: {},
+	}
+)
+
+type (
+	// Option is gateway option.
+	Option func(g *Gateway)
+
+	// Gateway serves the gRPC methods as REST routes derived from their google.api.http annotations.
+	// The calls are sent through a grpc.ClientConnInterface, e.g. a client of a discovered backend
+	// or a LocalConn serving the services in-process.
+	Gateway struct {
+		conn        grpc.ClientConnInterface
+		marshaler   protojson.MarshalOptions
+		unmarshaler protojson.UnmarshalOptions
+		maxBodySize int64
+	}
+
+	// binding is a http rule of a method.
+	binding struct {
+		method       protoreflect.MethodDescriptor
+		fullMethod   string
+		httpMethod   string
+		path         string
+		vars         []variable
+		body         string
+		responseBody string
+	}
+)
+
+// WithMarshalOptions sets the json options of the responses,
+// EmitUnpopulated is enabled by default.
+func WithMarshalOptions(opts protojson.MarshalOptions) Option {
+	return func(g *Gateway) {
+		g.marshaler = opts
+	}
+}
+
+// WithUnmarshalOptions sets the json options of the request bodies,
+// DiscardUnknown is enabled by default.
+func WithUnmarshalOptions(opts protojson.UnmarshalOptions) Option {
+	return func(g *Gateway) {
+		g.unmarshaler = opts
+	}
+}
+
+// WithMaxBodySize sets the max size of the request bodies, DefaultMaxBodySize by default,
+// the larger requests fail with 413.
+func WithMaxBodySize(size int64) Option {
+	return func(g *Gateway) {
+		g.maxBodySize = size
+	}
+}
+
+// New creates a gateway sending the calls through conn.
+func New(conn grpc.ClientConnInterface, opts ...Option) *Gateway {
+	g := &Gateway{
+		conn:        conn,
+		marshaler:   protojson.MarshalOptions{EmitUnpopulated: true},
+		unmarshaler: protojson.UnmarshalOptions{DiscardUnknown: true},
+		maxBodySize: DefaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Mount registers the routes of the services by their full names, e.g. kratos.api.Metadata.
+// The services must be linked into the binary, the methods without google.api.http annotation are skipped.
+func (g *Gateway) Mount(r gin.IRoutes, services ...string) (err error) {
+	var bindings []binding
+	for _, service := range services {
+		bs, err := serviceBindings(service)
+		if err != nil {
+			return err
+		}
+		bindings = append(bindings, bs...)
+	}
+	// gin的路由冲突会panic，转换成错误返回
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("mount gateway routes: %v", p)
+		}
+	}()
+	for _, b := range bindings {
+		log.Infof("[gateway] %-6s %s --> %s", b.httpMethod, b.path, b.fullMethod)
+		r.Handle(b.httpMethod, b.path, g.handler(b))
+	}
+	return nil
+}
+
+// serviceBindings returns the http rules of the methods of the service.
+func serviceBindings(service string) ([]binding, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("find service %s: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+
+	var bindings []binding
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		if md.IsStreamingClient() || md.IsStreamingServer() {
+			continue
+		}
+		rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			b, err := newBinding(md, r)
+			if err != nil {
+				return nil, err
+			}
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings, nil
+}
+
+func newBinding(md protoreflect.MethodDescriptor, rule *annotations.HttpRule) (binding, error) {
+	b := binding{
+		method:       md,
+		fullMethod:   fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name()),
+		body:         rule.GetBody(),
+		responseBody: rule.GetResponseBody(),
+	}
+	var tmpl string
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		b.httpMethod, tmpl = http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		b.httpMethod, tmpl = http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		b.httpMethod, tmpl = http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		b.httpMethod, tmpl = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		b.httpMethod, tmpl = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		b.httpMethod, tmpl = strings.ToUpper(p.Custom.GetKind()), p.Custom.GetPath()
+	default:
+		return b, fmt.Errorf("method %s has no http pattern", b.fullMethod)
+	}
+	path, vars, err := parseTemplate(tmpl)
+	if err != nil {
+		return b, fmt.Errorf("method %s: %w", b.fullMethod, err)
+	}
+	b.path, b.vars = path, vars
+	return b, nil
+}
+
+func (g *Gateway) handler(b binding) gin.HandlerFunc {
+	input, output := messageType(b.method.Input()), messageType(b.method.Output())
+	return func(c *gin.Context) {
+		req := input.New()
+		if err := g.bind(c, b, req); err != nil {
+			if err == errBodyTooLarge {
+				core.WriteResponse(c, codeError(err, http.StatusRequestEntityTooLarge, err.Error()), nil)
+				return
+			}
+			core.WriteResponse(c, httpError(status.Error(codes.InvalidArgument, err.Error())), nil)
+			return
+		}
+
+		reply := output.New()
+		ctx := metadata.NewOutgoingContext(c.Request.Context(), incomingMetadata(c.Request.Header))
+		if err := g.conn.Invoke(ctx, b.fullMethod, req.Interface(), reply.Interface()); err != nil {
+			core.WriteResponse(c, httpError(err), nil)
+			return
+		}
+
+		data, err := g.marshal(reply, b.responseBody)
+		if err != nil {
+			core.WriteResponse(c, err, nil)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
+
+// bind fills the request by the body, path params and query params.
+func (g *Gateway) bind(c *gin.Context, b binding, req protoreflect.Message) error {
+	bound := make(map[string]struct{})
+	if b.body != "" {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, g.maxBodySize))
+		if err != nil {
+			// go1.18没有http.MaxBytesError，读满上限后失败即请求体过大
+			if int64(len(body)) >= g.maxBodySize {
+				return errBodyTooLarge
+			}
+			return err
+		}
+		if len(body) > 0 {
+			if b.body != "*" {
+				// 用请求消息包装body字段，由protojson统一处理各种字段类型
+				fd := findField(req.Descriptor(), b.body)
+				if fd == nil {
+					return fmt.Errorf("body field %s not found", b.body)
+				}
+				body = []byte(fmt.Sprintf(`{%q:%s}`, fd.JSONName(), body))
+			}
+			if err = g.unmarshaler.Unmarshal(body, req.Interface()); err != nil {
+				return err
+			}
+		}
+		bound[b.body] = struct{}{}
+	}
+
+	for _, v := range b.vars {
+		if err := setField(req, v.field, []string{v.value(c.Param)}); err != nil {
+			return err
+		}
+		bound[v.field] = struct{}{}
+	}
+
+	if b.body == "*" {
+		return nil
+	}
+	for key, values := range c.Request.URL.Query() {
+		// 与grpc-gateway一致，忽略未知的查询参数
+		if isBound(bound, key) || !hasField(req.Descriptor(), key) {
+			continue
+		}
+		if err := setField(req, key, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBound reports whether the field path or its parent is bound by the body or path.
+func isBound(bound map[string]struct{}, path string) bool {
+	for {
+		if _, ok := bound[path]; ok {
+			return true
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+	}
+}
+
+// marshal returns the json of the reply, or the json of its field if responseBody is set.
+func (g *Gateway) marshal(reply protoreflect.Message, responseBody string) ([]byte, error) {
+	data, err := g.marshaler.Marshal(reply.Interface())
+	if err != nil || responseBody == "" {
+		return data, err
+	}
+	fd := findField(reply.Descriptor(), responseBody)
+	if fd == nil {
+		return nil, fmt.Errorf("response body field %s not found", responseBody)
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	name := fd.JSONName()
+	if g.marshaler.UseProtoNames {
+		name = string(fd.Name())
+	}
+	if field, ok := fields[name]; ok {
+		return field, nil
+	}
+	return []byte("null"), nil
+}
+
+// messageType returns the registered type of the message, or a dynamic type if it is not linked.
+func messageType(md protoreflect.MessageDescriptor) protoreflect.MessageType {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+		return mt
+	}
+	return dynamicpb.NewMessageType(md)
+}
+
+// incomingMetadata returns the gRPC metadata of the http headers,
+// Authorization, X-* and Grpc-Metadata-* headers are forwarded except the identity keys set by the services,
+// which the clients can not choose.
+func incomingMetadata(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, vs := range header {
+		switch {
+		case strings.HasPrefix(k, MetadataHeaderPrefix):
+			k = strings.TrimPrefix(k, MetadataHeaderPrefix)
+		case k == "Authorization", strings.HasPrefix(k, "X-"):
+		default:
+			continue
+		}
+		if _, ok := identityMetadata[strings.ToLower(k)]; ok {
+			continue
+		}
+		md.Append(k, vs...)
+	}
+	return md
+}
+
+// httpError returns the error with code of pkg/errors of the gRPC error, the status errors without code
+// get the reserved unknown code and the http status of their gRPC code, since the gRPC codes would collide
+// with the business codes.
+func httpError(err error) error {
+	err = rpcerrors.FromStatus(err)
+	if errors.HasCode(err) {
+		return err
+	}
+	st := status.Convert(err)
+	return codeError(err, rpcerrors.HTTPStatus(st.Code()), st.Message())
+}
+
+// codeError returns the error with the unknown code and the http status.
+func codeError(err error, httpStatus int, msg string) error {
+	return errors.WrapCoder(err, errors.NewCoder(unknownCode, httpStatus, msg, ""), "%s", msg)
+}
@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	apimetadata "mymicro/api/metadata"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl   string
+		path   string
+		values map[string]string
+		err    bool
+	}{
+		{tmpl: "/services", path: "/services", values: map[string]string{}},
+		{tmpl: "/services/{name}", path: "/services/:p0", values: map[string]string{"name": "a"}},
+		{tmpl: "/v1/{name=messages/*}/books/{book.id}", path: "/v1/messages/:p0/books/:p1",
+			values: map[string]string{"name": "messages/a", "book.id": "b"}},
+		{tmpl: "/v1/files/{path=**}", path: "/v1/files/*p0", values: map[string]string{"path": "a"}},
+		{tmpl: "/v1/{name}:cancel", err: true},
+		{tmpl: "/v1/{name", err: true},
+		{tmpl: "v1", err: true},
+	}
+	params := map[string]string{"p0": "a", "p1": "b"}
+	for _, tt := range tests {
+		path, vars, err := parseTemplate(tt.tmpl)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expect error %v, got %v", tt.tmpl, tt.err, err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if path != tt.path {
+			t.Errorf("%s: expect %s, got %s", tt.tmpl, tt.path, path)
+		}
+		values := make(map[string]string)
+		for _, v := range vars {
+			values[v.field] = v.value(func(name string) string { return params[name] })
+		}
+		if !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("%s: expect %v, got %v", tt.tmpl, tt.values, values)
+		}
+	}
+}
+
+func TestGateway(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := grpc.NewServer()
+	md := apimetadata.NewServer(srv)
+	apimetadata.RegisterMetadataServer(srv, md)
+	conn := NewLocalConn()
+	apimetadata.RegisterMetadataServer(conn, md)
+
+	r := gin.New()
+	if err := New(conn).Mount(r, "kratos.api.Metadata"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		check  func(body map[string]any) bool
+	}{
+		{path: "/services", status: http.StatusOK, check: func(body map[string]any) bool {
+			services, _ := body["services"].([]any)
+			return len(services) == 1 && services[0] == "kratos.api.Metadata"
+		}},
+		{path: "/services/kratos.api.Metadata", status: http.StatusOK, check: func(body map[string]any) bool {
+			return body["fileDescSet"] != nil
+		}},
+		{path: "/services/unknown", status: http.StatusNotFound, check: func(body map[string]any) bool {
+			return body["msg"] == "service unknown not found" && body["code"] == float64(unknownCode)
+		}},
+		{path: "/services/kratos.api.Metadata?unknown=1", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expect %d, got %d %s", tt.path, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if tt.check != nil && !tt.check(body) {
+			t.Errorf("%s: unexpected body %s", tt.path, w.Body.String())
+		}
+	}
+}
+
+func TestBindMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	g := New(nil, WithMaxBodySize(16))
+	b := binding{body: "*"}
+
+	tests := []struct {
+		body string
+		err  error
+	}{
+		{body: `{}`},
+		{body: `{"name":"kratos.api.Metadata.too.large"}`, err: errBodyTooLarge},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		req := (&apimetadata.GetServiceDescRequest{}).ProtoReflect()
+		if err := g.bind(c, b, req); err != tt.err {
+			t.Errorf("%s: expect %v, got %v", tt.body, tt.err, err)
+		}
+	}
+}
+
+func TestIncomingMetadata(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set("X-Request-Id", "1")
+	header.Set("X-Caller", "admin")
+	header.Set(MetadataHeaderPrefix+"Tenant", "a")
+	header.Set(MetadataHeaderPrefix+"X-Caller", "admin")
+	header.Set("Cookie", "session")
+
+	md := incomingMetadata(header)
+	want := metadata.Pairs("authorization", "Bearer token", "x-request-id", "1", "tenant", "a")
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("expect %v, got %v", want, md)
+	}
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type (
+	// LocalConn serves the unary methods of the registered services in-process,
+	// it is a grpc.ServiceRegistrar for the generated RegisterXxxServer and a grpc.ClientConnInterface for the gateway.
+	LocalConn struct {
+		interceptor grpc.UnaryServerInterceptor
+
+		mu      sync.RWMutex
+		methods map[string]localMethod
+	}
+
+	localMethod struct {
+		impl    any
+		handler func(srv any, ctx context.Context, dec func(any) error,
+			interceptor grpc.UnaryServerInterceptor) (any, error)
+	}
+)
+
+var (
+	_ grpc.ServiceRegistrar    = (*LocalConn)(nil)
+	_ grpc.ClientConnInterface = (*LocalConn)(nil)
+)
+
+var (
+	errLocalStreamNotSupported  = status.Error(codes.Unimplemented, "streams are not supported in-process")
+	errLocalMessageNotSupported = status.Error(codes.Internal, "messages must be proto.Message")
+)
+
+// NewLocalConn creates a LocalConn, the interceptors are chained in order around each call.
+func NewLocalConn(interceptors ...grpc.UnaryServerInterceptor) *LocalConn {
+	return &LocalConn{
+		interceptor: chainUnaryInterceptors(interceptors),
+		methods:     make(map[string]localMethod),
+	}
+}
+
+func (c *LocalConn) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range desc.Methods {
+		c.methods["/"+desc.ServiceName+"/"+m.MethodName] = localMethod{impl: impl, handler: m.Handler}
+	}
+}
+
+func (c *LocalConn) Invoke(ctx context.Context, method string, args any, reply any, _ ...grpc.CallOption) error {
+	c.mu.RLock()
+	m, ok := c.methods[method]
+	c.mu.RUnlock()
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", strings.TrimPrefix(method, "/"))
+	}
+	in, ok := args.(proto.Message)
+	if !ok {
+		return errLocalMessageNotSupported
+	}
+	out, ok := reply.(proto.Message)
+	if !ok {
+		return errLocalMessageNotSupported
+	}
+
+	// 出站metadata作为服务端的入站metadata
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	dec := func(v any) error {
+		return copyMessage(in, v)
+	}
+	resp, err := m.handler(m.impl, ctx, dec, c.interceptor)
+	if err != nil {
+		return err
+	}
+	return copyMessage(resp, out)
+}
+
+func (c *LocalConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errLocalStreamNotSupported
+}
+
+// copyMessage copies the message by its wire format, the types may differ, e.g. a dynamic message.
+func copyMessage(from any, to any) error {
+	src, ok := from.(proto.Message)
+	if !ok {
+		return errLocalMessageNotSupported
+	}
+	dst, ok := to.(proto.Message)
+	if !ok {
+		return errLocalMessageNotSupported
+	}
+	b, err := proto.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, dst)
+}
+
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return interceptors[0](ctx, req, info, chainedHandler(interceptors, 0, info, handler))
+	}
+}
+
+func chainedHandler(interceptors []grpc.UnaryServerInterceptor, cur int, info *grpc.UnaryServerInfo,
+	final grpc.UnaryHandler) grpc.UnaryHandler {
+	if cur == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, req any) (any, error) {
+		return interceptors[cur+1](ctx, req, info, chainedHandler(interceptors, cur+1, info, final))
+	}
+}
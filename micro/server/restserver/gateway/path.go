@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+)
+
+// variable is a variable of the path template, e.g. {name=messages/*},
+// its value is the pattern segments joined by "/" with the wildcards replaced by the route params.
+type variable struct {
+	field    string
+	segments []string
+	// params are the route params of the wildcard segments, "" for literal segments.
+	params []string
+}
+
+// value returns the value of the variable from the route params.
+func (v variable) value(param func(name string) string) string {
+	values := make([]string, len(v.segments))
+	for i, seg := range v.segments {
+		if v.params[i] == "" {
+			values[i] = seg
+			continue
+		}
+		values[i] = strings.TrimPrefix(param(v.params[i]), "/")
+	}
+	return strings.Join(values, "/")
+}
+
+// parseTemplate converts the path template of google.api.http into the gin route path,
+// e.g. /v1/{name=messages/*}/books/{book_id} into /v1/messages/:p0/books/:p1.
+// Custom verbs are not supported.
+func parseTemplate(tmpl string) (string, []variable, error) {
+	if !strings.HasPrefix(tmpl, "/") {
+		return "", nil, fmt.Errorf("path template %s must start with /", tmpl)
+	}
+	segs, err := splitTemplate(tmpl[1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid path template %s: %w", tmpl, err)
+	}
+
+	var (
+		route []string
+		vars  []variable
+		n     int
+	)
+	wildcard := func(seg string) string {
+		name := fmt.Sprintf("p%d", n)
+		n++
+		if seg == "**" {
+			return "*" + name
+		}
+		return ":" + name
+	}
+	for _, seg := range segs {
+		if strings.Contains(seg, ":") && !strings.HasPrefix(seg, "{") || strings.Contains(seg, "}:") {
+			return "", nil, fmt.Errorf("custom verb of path template %s is not supported", tmpl)
+		}
+		if strings.HasPrefix(seg, "{") != strings.HasSuffix(seg, "}") {
+			return "", nil, fmt.Errorf("invalid segment %s of path template %s", seg, tmpl)
+		}
+		if !strings.HasPrefix(seg, "{") {
+			if seg == "*" || seg == "**" {
+				seg = wildcard(seg)
+			}
+			route = append(route, seg)
+			continue
+		}
+
+		field, pattern := seg[1:len(seg)-1], "*"
+		if i := strings.Index(field, "="); i >= 0 {
+			field, pattern = field[:i], field[i+1:]
+		}
+		v := variable{field: field}
+		for _, p := range strings.Split(pattern, "/") {
+			v.segments = append(v.segments, p)
+			if p == "*" || p == "**" {
+				param := wildcard(p)
+				v.params = append(v.params, param[1:])
+				route = append(route, param)
+			} else {
+				v.params = append(v.params, "")
+				route = append(route, p)
+			}
+		}
+		vars = append(vars, v)
+	}
+	path := "/" + strings.Join(route, "/")
+	if i := strings.Index(path, "/*"); i >= 0 && strings.Contains(path[i+2:], "/") {
+		return "", nil, fmt.Errorf("** of path template %s must be the last segment", tmpl)
+	}
+	return path, vars, nil
+}
+
+// splitTemplate splits the template by the "/" outside the variables.
+func splitTemplate(tmpl string) ([]string, error) {
+	var (
+		segs  []string
+		depth int
+		start int
+	)
+	for i, c := range tmpl {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				segs = append(segs, tmpl[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, fmt.Errorf("unbalanced braces")
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced braces")
+	}
+	return append(segs, tmpl[start:]), nil
+}
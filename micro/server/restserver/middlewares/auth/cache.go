@@ -55,48 +55,58 @@ func (cache CacheStrategy) AuthFunc() gin.HandlerFunc {
 		// Parse the header to get the token part.
 		fmt.Sscanf(header, "Bearer %s", &rawJWT)
 
-		// Use own validation logic, see below
-		var secret Secret
-
-		claims := &jwt.MapClaims{}
-		// Verify the token
-		parsedT, err := jwt.ParseWithClaims(rawJWT, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg is HMAC signature
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-
-			kid, ok := token.Header["kid"].(string)
-			if !ok {
-				return nil, ErrMissingKID
-			}
-
-			var err error
-			secret, err = cache.get(kid)
-			if err != nil {
-				return nil, ErrMissingSecret
-			}
-
-			return []byte(secret.Key), nil
-		}, jwt.WithAudience(AuthzAudience))
-		if err != nil || !parsedT.Valid {
+		secret, err := cache.Verify(rawJWT)
+		if err != nil {
 			core.WriteResponse(c, errors.WithCode(401, err.Error()), nil)
 			c.Abort()
 
 			return
 		}
 
-		if KeyExpired(secret.Expires) {
-			tm := time.Unix(secret.Expires, 0).Format("2006-01-02 15:04:05")
-			core.WriteResponse(c, errors.WithCode(401, "expired at: %s", tm), nil)
-			c.Abort()
+		c.Set(middlewares.UsernameKey, secret.Username)
+		c.Next()
+	}
+}
 
-			return
+// Verify verifies the jwt with the secret of its kid, and returns the secret.
+func (cache CacheStrategy) Verify(rawJWT string) (Secret, error) {
+	// Use own validation logic, see below
+	var secret Secret
+
+	claims := &jwt.MapClaims{}
+	// Verify the token
+	parsedT, err := jwt.ParseWithClaims(rawJWT, claims, func(token *jwt.Token) (interface{}, error) {
+		// Validate the alg is HMAC signature
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		c.Set(middlewares.UsernameKey, secret.Username)
-		c.Next()
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrMissingKID
+		}
+
+		var err error
+		secret, err = cache.get(kid)
+		if err != nil {
+			return nil, ErrMissingSecret
+		}
+
+		return []byte(secret.Key), nil
+	}, jwt.WithAudience(AuthzAudience))
+	if err != nil {
+		return Secret{}, err
 	}
+	if !parsedT.Valid {
+		return Secret{}, errors.New("invalid token")
+	}
+
+	if KeyExpired(secret.Expires) {
+		tm := time.Unix(secret.Expires, 0).Format("2006-01-02 15:04:05")
+		return Secret{}, errors.Errorf("expired at: %s", tm)
+	}
+
+	return secret, nil
 }
 
 // KeyExpired checks if a key has expired, if the value of user.SessionState.Expires is 0, it will be ignored.
@@ -1,21 +1,62 @@
 package middlewares
 
 import (
-	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
 )
 
+// CorsConf is the configuration of the Cors middleware.
+type CorsConf struct {
+	AllowOrigins     []string `json:"allowOrigins" mapstructure:"allowOrigins"`
+	AllowHeaders     []string `json:"allowHeaders" mapstructure:"allowHeaders"`
+	AllowMethods     []string `json:"allowMethods" mapstructure:"allowMethods"`
+	ExposeHeaders    []string `json:"exposeHeaders" mapstructure:"exposeHeaders"`
+	AllowCredentials bool     `json:"allowCredentials" mapstructure:"allowCredentials"`
+}
+
+// DefaultCorsConf returns the configuration of Cors, all origins are allowed.
+func DefaultCorsConf() CorsConf {
+	return CorsConf{
+		AllowOrigins:     []string{"*"},
+		AllowHeaders:     []string{"Content-Type", "AccessToken", "X-CSRF-Token", "Authorization", "Token", "x-token", "Bearer"},
+		AllowMethods:     []string{"POST", "GET", "OPTIONS", "DELETE", "PATCH", "PUT"},
+		ExposeHeaders:    []string{"Content-Length", "Access-Control-Allow-Origin", "Access-Control-Allow-Headers", "Content-Type"},
+		AllowCredentials: true,
+	}
+}
+
+// SetHeaders sets the CORS headers of the response to the request from origin.
+func (conf CorsConf) SetHeaders(h http.Header, origin string) {
+	allowOrigin := ""
+	for _, o := range conf.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			allowOrigin = o
+			break
+		}
+	}
+	if allowOrigin == "" {
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", allowOrigin)
+	h.Set("Access-Control-Allow-Headers", strings.Join(conf.AllowHeaders, ", "))
+	h.Set("Access-Control-Allow-Methods", strings.Join(conf.AllowMethods, ", "))
+	h.Set("Access-Control-Expose-Headers", strings.Join(conf.ExposeHeaders, ", "))
+	h.Set("Access-Control-Allow-Credentials", strconv.FormatBool(conf.AllowCredentials))
+}
+
 func Cors() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		method := c.Request.Method
+	return CorsWithConf(DefaultCorsConf())
+}
 
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Headers", "Content-Type,AccessToken,X-CSRF-Token, Authorization, Token, x-token, Bearer")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, DELETE, PATCH, PUT")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-		c.Header("Access-Control-Allow-Credentials", "true")
+// CorsWithConf returns the Cors middleware of the configuration.
+func CorsWithConf(conf CorsConf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf.SetHeaders(c.Writer.Header(), c.GetHeader("Origin"))
 
-		if method == "OPTIONS" {
+		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
 		}
 	}
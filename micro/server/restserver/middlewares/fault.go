@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mymicro/micro/core/fault"
+)
+
+// Fault injects the faults of the injector into requests, the rules are keyed by "METHOD route",
+// the caller and the headers, e.g. "GET /v1/users/:id", caller is Caller if nil.
+// Abort closes the connection without response, or responds 503 if the connection can not be hijacked, e.g. HTTP/2.
+func Fault(injector *fault.Injector, caller func(c *gin.Context) string) gin.HandlerFunc {
+	if caller == nil {
+		caller = Caller
+	}
+	return func(c *gin.Context) {
+		// 管理接口不注入故障，否则规则可能无法被撤销
+		if c.FullPath() == fault.DefaultAdminPath {
+			c.Next()
+			return
+		}
+		name := c.Request.Method + " " + c.FullPath()
+		rule, ok := injector.Fire(name, caller(c), c.GetHeader)
+		if !ok {
+			c.Next()
+			return
+		}
+		if err := injector.Wait(c.Request.Context(), rule); err != nil {
+			c.Abort()
+			return
+		}
+		switch {
+		case rule.Abort:
+			if conn, _, err := c.Writer.Hijack(); err == nil {
+				_ = conn.Close()
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, map[string]string{
+				"msg": rule.Error(),
+			})
+			return
+		case rule.HTTPStatus != 0:
+			c.AbortWithStatusJSON(rule.HTTPStatus, map[string]string{
+				"msg": rule.Error(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
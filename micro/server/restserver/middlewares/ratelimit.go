@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mymicro/micro/core/ratelimit"
+)
+
+// CallerHeader is the header of the calling service or user.
+const CallerHeader = "x-caller"
+
+// Caller returns the user id of the jwt claims, the CallerHeader, or the client ip in order.
+// The CallerHeader is only trusted from the clients verified by client certificates,
+// since the other clients can send any value, use TrustedCaller to trust it from all the clients.
+func Caller(c *gin.Context) string {
+	return caller(c, false)
+}
+
+// TrustedCaller is Caller trusting the CallerHeader of all the clients,
+// e.g. when the server is only reachable by the trusted services.
+func TrustedCaller(c *gin.Context) string {
+	return caller(c, true)
+}
+
+func caller(c *gin.Context, trustAll bool) string {
+	if v, ok := c.Get("claims"); ok {
+		if claims, ok := v.(*CustomClaims); ok {
+			return strconv.FormatUint(uint64(claims.ID), 10)
+		}
+	}
+	if trustAll || (c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0) {
+		if caller := c.GetHeader(CallerHeader); caller != "" {
+			return caller
+		}
+	}
+	return c.ClientIP()
+}
+
+// JWTCaller returns the user id of the x-token verified with signKey, or fallback if the token is missing
+// or invalid, fallback is Caller if nil.
+// It identifies the users without JWTAuth, e.g. for the limiter installed on all the routes
+// which runs before the JWTAuth of each route.
+func JWTCaller(signKey string, fallback func(c *gin.Context) string) func(c *gin.Context) string {
+	if fallback == nil {
+		fallback = Caller
+	}
+	j := NewJWT(signKey)
+	return func(c *gin.Context) string {
+		if token := c.GetHeader("x-token"); token != "" {
+			if claims, err := j.ParseToken(token); err == nil {
+				return strconv.FormatUint(uint64(claims.ID), 10)
+			}
+		}
+		return fallback(c)
+	}
+}
+
+// RateLimit rejects requests over quota with 429, the rules are keyed by "METHOD route" and the caller,
+// e.g. "GET /v1/users/:id", caller is Caller if nil.
+// Caller only sees the claims of JWTAuth if RateLimit runs after it, e.g. on the route after JWTAuth,
+// otherwise use JWTCaller.
+func RateLimit(limiter *ratelimit.RuleLimiter, caller func(c *gin.Context) string) gin.HandlerFunc {
+	if caller == nil {
+		caller = Caller
+	}
+	return func(c *gin.Context) {
+		name := c.Request.Method + " " + c.FullPath()
+		if err := limiter.Allow(c.Request.Context(), name, caller(c)); err != nil {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, map[string]string{
+				"msg": err.Error(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
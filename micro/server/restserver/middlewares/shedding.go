@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mymicro/micro/core/load"
+)
+
+// Shedding rejects requests with 503 when the shedder reports the server is overloaded.
+func Shedding(shedder load.Shedder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done, err := shedder.Allow()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, map[string]string{
+				"msg": err.Error(),
+			})
+			return
+		}
+		defer done()
+
+		c.Next()
+	}
+}
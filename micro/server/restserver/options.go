@@ -1,5 +1,13 @@
 package restserver
 
+import (
+	"github.com/gin-gonic/gin"
+
+	"mymicro/micro/core/fault"
+	"mymicro/micro/core/load"
+	"mymicro/micro/core/ratelimit"
+)
+
 type ServerOption func(*Server)
 
 func WithPort(port int) ServerOption {
@@ -55,3 +63,37 @@ func WithTransName(transName string) ServerOption {
 		s.transName = transName
 	}
 }
+
+// WithShedder enables adaptive load shedding, e.g. load.NewAdaptiveShedder().
+func WithShedder(shedder load.Shedder) ServerOption {
+	return func(s *Server) {
+		s.shedder = shedder
+	}
+}
+
+// WithRateLimiter enables the rate limit of each route and caller on all the routes,
+// the users are identified by the x-token verified with the key of WithJwt.
+func WithRateLimiter(limiter *ratelimit.RuleLimiter) ServerOption {
+	return func(s *Server) {
+		s.rateLimiter = limiter
+	}
+}
+
+// WithTrustCallerHeader trusts the x-caller header of all the clients to identify the callers of the rate limiter
+// and the fault rules, it is only trusted from the clients verified by client certificates by default.
+// Enable it only if the server is reachable by the trusted services alone.
+func WithTrustCallerHeader(trust bool) ServerOption {
+	return func(s *Server) {
+		s.trustCallerHeader = trust
+	}
+}
+
+// WithFaultInjector injects the faults into the routes if the injector is enabled.
+// The admin endpoint of the rules is served on fault.DefaultAdminPath only if adminAuth is set,
+// it runs after all the middlewares and must reject the requests of non-admin users by aborting them.
+func WithFaultInjector(injector *fault.Injector, adminAuth gin.HandlerFunc) ServerOption {
+	return func(s *Server) {
+		s.faultInjector = injector
+		s.faultAdminAuth = adminAuth
+	}
+}
@@ -5,12 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/penglongli/gin-metrics/ginmetrics"
+	"google.golang.org/grpc"
 
+	"mymicro/micro/core/fault"
+	"mymicro/micro/core/load"
+	"mymicro/micro/core/ratelimit"
+	"mymicro/micro/server/grpcweb"
+	"mymicro/micro/server/restserver/gateway"
 	mws "mymicro/micro/server/restserver/middlewares"
 	"mymicro/micro/server/restserver/pprof"
 	"mymicro/micro/server/restserver/validation"
@@ -44,6 +51,16 @@ type Server struct {
 
 	server      *http.Server
 	serviceName string
+	shedder     load.Shedder
+	rateLimiter *ratelimit.RuleLimiter
+	// 是否信任客户端发送的x-caller
+	trustCallerHeader bool
+	// 故障注入，仅在配置开启时生效
+	faultInjector  *fault.Injector
+	faultAdminAuth gin.HandlerFunc
+
+	initOnce sync.Once
+	initErr  error
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -67,6 +84,24 @@ func NewServer(opts ...ServerOption) *Server {
 	}
 
 	srv.Use(mws.TracingHandler(srv.serviceName))
+	if srv.shedder != nil {
+		srv.Use(mws.Shedding(srv.shedder))
+	}
+	caller := mws.Caller
+	if srv.trustCallerHeader {
+		caller = mws.TrustedCaller
+	}
+	if srv.rateLimiter != nil {
+		// 全局限流先于各路由的JWTAuth执行，自行校验token识别用户
+		limitCaller := caller
+		if srv.jwt != nil {
+			limitCaller = mws.JWTCaller(srv.jwt.Key, caller)
+		}
+		srv.Use(mws.RateLimit(srv.rateLimiter, limitCaller))
+	}
+	if srv.faultInjector.Enabled() {
+		srv.Use(mws.Fault(srv.faultInjector, caller))
+	}
 
 	for _, m := range srv.middlewares {
 		mw, ok := mws.Middlewares[m]
@@ -77,10 +112,68 @@ func NewServer(opts ...ServerOption) *Server {
 		log.Infof("Install middleware: %s", m)
 		srv.Use(mw)
 	}
+
+	// 管理接口在所有中间件之后注册，并且必须显式配置鉴权
+	if srv.faultInjector.Enabled() {
+		if srv.faultAdminAuth != nil {
+			srv.Any(fault.DefaultAdminPath, srv.faultAdminAuth, gin.WrapH(srv.faultInjector))
+		} else {
+			log.Warnf("Fault admin endpoint is not served without admin auth")
+		}
+	}
 	return srv
 }
 
+// MountGRPC serves the gRPC services as REST routes derived from their google.api.http annotations,
+// the calls are sent through conn, e.g. a rpcserver client of a discovered backend or a gateway.LocalConn.
+func (s *Server) MountGRPC(conn grpc.ClientConnInterface, services []string, opts ...gateway.Option) error {
+	return gateway.New(conn, opts...).Mount(s.Engine, services...)
+}
+
+// MountGRPCWeb serves the services registered on server to gRPC-Web clients of browsers,
+// e.g. the grpc.Server of rpcserver, client streaming methods are not supported by gRPC-Web.
+func (s *Server) MountGRPCWeb(server *grpc.Server, opts ...grpcweb.Option) {
+	h := gin.WrapH(grpcweb.NewHandler(server, opts...))
+	for name, info := range server.GetServiceInfo() {
+		for _, m := range info.Methods {
+			if m.IsClientStream {
+				continue
+			}
+			path := "/" + name + "/" + m.Name
+			s.POST(path, h)
+			s.OPTIONS(path, h)
+		}
+	}
+}
+
 func (s *Server) Start(ctx context.Context) error {
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Rest server is running on port: %d", s.port)
+	address := fmt.Sprintf(":%d", s.port)
+	s.server = &http.Server{
+		Addr:    address,
+		Handler: handler,
+	}
+	if err = s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler initializes the server and returns its http handler without listening,
+// e.g. to serve it on the shared listener of muxserver instead of Start.
+func (s *Server) Handler() (http.Handler, error) {
+	s.initOnce.Do(func() {
+		s.initErr = s.init()
+	})
+	return s.Engine, s.initErr
+}
+
+func (s *Server) init() error {
 	if s.mode != gin.DebugMode && s.mode != gin.ReleaseMode && s.mode != gin.TestMode {
 		return errors.New("mode must be one of debug/release/test")
 	}
@@ -111,21 +204,14 @@ func (s *Server) Start(ctx context.Context) error {
 
 		m.Use(s)
 	}
-
-	log.Infof("Rest server is running on port: %d", s.port)
-	address := fmt.Sprintf(":%d", s.port)
-	s.server = &http.Server{
-		Addr:    address,
-		Handler: s.Engine,
-	}
 	_ = s.SetTrustedProxies(nil)
-	if err = s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return err
-	}
 	return nil
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
 	log.Infof("Rest server is stopping")
 	if err := s.server.Shutdown(ctx); err != nil {
 		log.Errorf("Rest server shutdown error: %s", err.Error())
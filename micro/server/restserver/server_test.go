@@ -0,0 +1,171 @@
+package restserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"mymicro/micro/core/fault"
+	"mymicro/micro/core/load"
+	"mymicro/micro/core/ratelimit"
+	mws "mymicro/micro/server/restserver/middlewares"
+)
+
+func TestFaultAdminAuth(t *testing.T) {
+	const adminToken = "admin-token"
+	adminAuth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer "+adminToken {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+	rules := `[{"name":"*","percentage":100,"abort":true}]`
+
+	tests := []struct {
+		name   string
+		auth   gin.HandlerFunc
+		token  string
+		status int
+	}{
+		{name: "no admin auth", status: http.StatusNotFound},
+		{name: "unauthenticated", auth: adminAuth, status: http.StatusUnauthorized},
+		{name: "admin", auth: adminAuth, token: adminToken, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector, err := fault.NewInjector(fault.Conf{Enabled: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := NewServer(WithMode(gin.TestMode), WithEnableProfiling(false), WithFaultInjector(injector, tt.auth))
+			handler, err := srv.Handler()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, fault.DefaultAdminPath, strings.NewReader(rules))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expect %d, got %d", tt.status, rec.Code)
+			}
+			if n := len(injector.Rules()); (n == 1) != (tt.status == http.StatusOK) {
+				t.Errorf("expect rules changed only by admin, got %d rules", n)
+			}
+		})
+	}
+}
+
+func TestRateLimitJWTCaller(t *testing.T) {
+	const key = "test-key"
+	token := func(id uint) string {
+		tk, err := mws.NewJWT(key).CreateToken(mws.CustomClaims{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tk
+	}
+	rule := ratelimit.Rule{Name: ratelimit.AnyName, Rate: 1, Burst: 1, PerCaller: true}
+	srv := NewServer(WithMode(gin.TestMode), WithEnableProfiling(false), WithJwt(&JwtInfo{Key: key}),
+		WithRateLimiter(ratelimit.NewRuleLimiter(ratelimit.NewLocalLimiter(), rule)))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	srv.GET("/global", ok)
+	// 路由级限流在JWTAuth之后，由claims识别用户
+	routeLimiter := ratelimit.NewRuleLimiter(ratelimit.NewLocalLimiter(), rule)
+	srv.GET("/route", mws.JWTAuth(key), mws.RateLimit(routeLimiter, nil), ok)
+	handler, err := srv.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all the requests are from the same ip
+	tests := []struct {
+		path   string
+		token  string
+		caller string
+		status int
+	}{
+		{path: "/global", token: token(1), status: http.StatusOK},
+		{path: "/global", token: token(1), status: http.StatusTooManyRequests},
+		{path: "/global", token: token(2), status: http.StatusOK},
+		{path: "/global", token: "invalid", status: http.StatusOK},
+		{path: "/global", status: http.StatusTooManyRequests},
+		// x-caller of the unauthenticated clients is not trusted
+		{path: "/global", caller: "spoofed", status: http.StatusTooManyRequests},
+		{path: "/route", token: token(3), status: http.StatusOK},
+		{path: "/route", token: token(3), status: http.StatusTooManyRequests},
+		{path: "/route", token: token(4), status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("x-token", tt.token)
+		}
+		if tt.caller != "" {
+			req.Header.Set(mws.CallerHeader, tt.caller)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expect %d, got %d", tt.path, tt.token, tt.status, rec.Code)
+		}
+	}
+}
+
+// testShedder sheds all requests if overloaded, and counts the done calls of the allowed ones.
+type testShedder struct {
+	overloaded bool
+	done       int
+}
+
+func (s *testShedder) Allow() (load.DoneFunc, error) {
+	if s.overloaded {
+		return nil, load.ErrServiceOverloaded
+	}
+	return func() { s.done++ }, nil
+}
+
+func TestShedding(t *testing.T) {
+	tests := []struct {
+		name       string
+		overloaded bool
+		status     int
+	}{
+		{name: "allowed", status: http.StatusOK},
+		{name: "overloaded", overloaded: true, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shedder := &testShedder{overloaded: tt.overloaded}
+			srv := NewServer(WithMode(gin.TestMode), WithEnableProfiling(false), WithShedder(shedder))
+			var called bool
+			srv.GET("/shedding", func(c *gin.Context) {
+				called = true
+				c.Status(http.StatusOK)
+			})
+			handler, err := srv.Handler()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shedding", nil))
+			if rec.Code != tt.status {
+				t.Errorf("expect %d, got %d", tt.status, rec.Code)
+			}
+			if called == tt.overloaded {
+				t.Errorf("expect handler called %v, got %v", !tt.overloaded, called)
+			}
+			if !tt.overloaded && shedder.done != 1 {
+				t.Errorf("expect done once, got %d", shedder.done)
+			}
+		})
+	}
+}
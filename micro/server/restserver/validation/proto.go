@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"regexp"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
+// protoRule matches the reasons of protoc-gen-validate errors, the submatches are the params of the translation.
+type protoRule struct {
+	key     string
+	pattern *regexp.Regexp
+	en      string
+	zh      string
+}
+
+var protoRules = []protoRule{
+	{
+		key:     "pgv_required",
+		pattern: regexp.MustCompile(`^value is required$`),
+		en:      "{0} is a required field",
+		zh:      "{0}为必填字段",
+	},
+	{
+		key:     "pgv_len",
+		pattern: regexp.MustCompile(`^value length must be (\d+) (?:runes|bytes)$`),
+		en:      "{0} must be {1} characters in length",
+		zh:      "{0}长度必须是{1}个字符",
+	},
+	{
+		key:     "pgv_min_len",
+		pattern: regexp.MustCompile(`^value length must be at least (\d+) (?:runes|bytes)$`),
+		en:      "{0} must be at least {1} characters in length",
+		zh:      "{0}长度必须至少为{1}个字符",
+	},
+	{
+		key:     "pgv_max_len",
+		pattern: regexp.MustCompile(`^value length must be at most (\d+) (?:runes|bytes)$`),
+		en:      "{0} must be a maximum of {1} characters in length",
+		zh:      "{0}长度不能超过{1}个字符",
+	},
+	{
+		key:     "pgv_gte",
+		pattern: regexp.MustCompile(`^value must be greater than or equal to (.+)$`),
+		en:      "{0} must be {1} or greater",
+		zh:      "{0}必须大于或等于{1}",
+	},
+	{
+		key:     "pgv_gt",
+		pattern: regexp.MustCompile(`^value must be greater than (.+)$`),
+		en:      "{0} must be greater than {1}",
+		zh:      "{0}必须大于{1}",
+	},
+	{
+		key:     "pgv_lte",
+		pattern: regexp.MustCompile(`^value must be less than or equal to (.+)$`),
+		en:      "{0} must be {1} or less",
+		zh:      "{0}必须小于或等于{1}",
+	},
+	{
+		key:     "pgv_lt",
+		pattern: regexp.MustCompile(`^value must be less than (.+)$`),
+		en:      "{0} must be less than {1}",
+		zh:      "{0}必须小于{1}",
+	},
+	{
+		key:     "pgv_in",
+		pattern: regexp.MustCompile(`^value must be in list (.+)$`),
+		en:      "{0} must be one of {1}",
+		zh:      "{0}必须是{1}中的一个",
+	},
+	{
+		key:     "pgv_email",
+		pattern: regexp.MustCompile(`^value must be a valid email address`),
+		en:      "{0} must be a valid email address",
+		zh:      "{0}必须是一个有效的邮箱",
+	},
+	{
+		key:     "pgv_pattern",
+		pattern: regexp.MustCompile(`^value does not match regex pattern (.+)$`),
+		en:      "{0} does not match the pattern {1}",
+		zh:      "{0}格式不正确",
+	},
+}
+
+func registerProtoTranslations(trans ut.Translator, locale string) error {
+	for _, r := range protoRules {
+		text := r.en
+		if locale == "zh" {
+			text = r.zh
+		}
+		if err := trans.Add(r.key, text, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TranslateProtoReason translates the reason of a protoc-gen-validate error of field,
+// the reason is returned as is if trans is nil or there is no translation of it.
+func TranslateProtoReason(trans ut.Translator, field, reason string) string {
+	if trans == nil {
+		return reason
+	}
+	for _, r := range protoRules {
+		m := r.pattern.FindStringSubmatch(reason)
+		if m == nil {
+			continue
+		}
+		params := append([]string{field}, m[1:]...)
+		if s, err := trans.T(r.key, params...); err == nil {
+			return s
+		}
+		break
+	}
+	return reason
+}
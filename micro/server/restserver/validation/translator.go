@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// NewTranslator returns the translator of locale with the translations of gin validator and protobuf validation,
+// it returns nil if the validator engine of gin is not go-playground/validator.
+func NewTranslator(locale string) (ut.Translator, error) {
+	//修改gin框架中的validator引擎属性, 实现定制
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil, nil
+	}
+	//注册一个获取json的tag的自定义方法
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	zhT := zh.New() //中文翻译器
+	enT := en.New() //英文翻译器
+	//第一个参数是备用的语言环境，后面的参数是应该支持的语言环境
+	uni := ut.New(enT, zhT, enT)
+	trans, ok := uni.GetTranslator(locale)
+	if !ok {
+		return nil, fmt.Errorf("uni.GetTranslator(%s)", locale)
+	}
+
+	var err error
+	switch locale {
+	case "zh":
+		err = zh_translations.RegisterDefaultTranslations(v, trans)
+	default:
+		err = en_translations.RegisterDefaultTranslations(v, trans)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = registerProtoTranslations(trans, locale); err != nil {
+		return nil, err
+	}
+	return trans, nil
+}
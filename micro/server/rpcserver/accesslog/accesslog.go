@@ -0,0 +1,203 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"mymicro/pkg/log"
+)
+
+const redacted = "***"
+
+type (
+	// Conf defines the access log of gRPC calls.
+	Conf struct {
+		// SampleRate is the ratio of the normal calls to log, in [0, 1], failed and slow calls are always logged.
+		SampleRate float64 `json:"sample-rate" mapstructure:"sample-rate"`
+		// SlowThreshold promotes the calls slower than it to warn level, 0 disables it.
+		SlowThreshold time.Duration `json:"slow-threshold" mapstructure:"slow-threshold"`
+		// LogPayload logs the request and response messages.
+		LogPayload bool `json:"log-payload" mapstructure:"log-payload"`
+		// RedactFields are the fields whose values are replaced in payloads, matched as the suffixes of
+		// the field names case-insensitively and ignoring underscores, e.g. "password" matches "user_password"
+		// and "newPassword".
+		RedactFields []string `json:"redact-fields" mapstructure:"redact-fields"`
+	}
+
+	// Entry is an access log entry of a gRPC call.
+	Entry struct {
+		// Kind is "server" or "client".
+		Kind     string
+		Method   string
+		Peer     string
+		Caller   string
+		Code     codes.Code
+		Err      error
+		Latency  time.Duration
+		Request  interface{}
+		Response interface{}
+	}
+
+	// Logger writes access logs by the Conf.
+	Logger struct {
+		conf   Conf
+		redact []string
+	}
+)
+
+// DefaultConf returns the default access log conf.
+func DefaultConf() Conf {
+	return Conf{
+		SampleRate:    1,
+		SlowThreshold: 500 * time.Millisecond,
+		RedactFields:  []string{"password", "token", "secret"},
+	}
+}
+
+// NewLogger returns an access logger.
+func NewLogger(conf Conf) *Logger {
+	redact := make([]string, 0, len(conf.RedactFields))
+	for _, f := range conf.RedactFields {
+		if f = normalize(f); f != "" {
+			redact = append(redact, f)
+		}
+	}
+	return &Logger{conf: conf, redact: redact}
+}
+
+// Log writes the entry, server errors are logged at error level, slow calls at warn level and the others at info level,
+// only the successful calls not slow are sampled.
+func (l *Logger) Log(ctx context.Context, e Entry) {
+	slow := l.conf.SlowThreshold > 0 && e.Latency > l.conf.SlowThreshold
+	if l.sampledOut(e, slow) {
+		return
+	}
+	serverErr := isServerError(e.Code)
+
+	fields := []log.Field{
+		log.String("kind", e.Kind),
+		log.String("method", e.Method),
+		log.String("peer", e.Peer),
+		log.String("caller", e.Caller),
+		log.String("code", e.Code.String()),
+		log.Float64("latency_ms", float64(e.Latency)/float64(time.Millisecond)),
+		log.Int("req_size", size(e.Request)),
+		log.Int("resp_size", size(e.Response)),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields,
+			log.String("trace_id", sc.TraceID().String()),
+			log.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if e.Err != nil {
+		fields = append(fields, log.String("error", e.Err.Error()))
+	}
+	if l.conf.LogPayload {
+		fields = append(fields,
+			log.String("request", l.payload(e.Request)),
+			log.String("response", l.payload(e.Response)),
+		)
+	}
+
+	msg := "[gRPC] " + e.Kind + " access"
+	switch {
+	case serverErr:
+		log.ErrorC(ctx, msg, fields...)
+	case slow:
+		log.WarnC(ctx, msg+" slow call", fields...)
+	default:
+		log.InfoC(ctx, msg, fields...)
+	}
+}
+
+// sampledOut reports whether the entry is dropped by sampling, failed and slow calls are never dropped.
+func (l *Logger) sampledOut(e Entry, slow bool) bool {
+	if slow || e.Code != codes.OK {
+		return false
+	}
+	return l.conf.SampleRate <= 0 || rand.Float64() >= l.conf.SampleRate
+}
+
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+func size(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok && msg != nil {
+		return proto.Size(msg)
+	}
+	return 0
+}
+
+// payload returns the json of m with the redact fields replaced.
+func (l *Logger) payload(m interface{}) string {
+	if m == nil {
+		return ""
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if msg, ok := m.(proto.Message); ok {
+		data, err = protojson.Marshal(msg)
+	} else {
+		data, err = json.Marshal(m)
+	}
+	if err != nil || len(l.redact) == 0 {
+		return string(data)
+	}
+
+	var v interface{}
+	if err = json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+	data, _ = json.Marshal(l.redactValue(v))
+	return string(data)
+}
+
+func (l *Logger) redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range val {
+			if l.redacted(k) {
+				val[k] = redacted
+				continue
+			}
+			val[k] = l.redactValue(fv)
+		}
+	case []interface{}:
+		for i, fv := range val {
+			val[i] = l.redactValue(fv)
+		}
+	}
+	return v
+}
+
+// redacted reports whether the field name ends with any of the redact fields.
+func (l *Logger) redacted(field string) bool {
+	field = normalize(field)
+	for _, f := range l.redact {
+		if strings.HasSuffix(field, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(field string) string {
+	return strings.ToLower(strings.ReplaceAll(field, "_", ""))
+}
@@ -0,0 +1,57 @@
+package accesslog
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPayloadRedaction(t *testing.T) {
+	l := NewLogger(Conf{RedactFields: []string{"password", "access_token"}})
+	msg, err := structpb.NewStruct(map[string]interface{}{
+		"name":          "foo",
+		"password":      "bar",
+		"user_password": "bar",
+		"passwordHint":  "foo",
+		"tokens": []interface{}{
+			map[string]interface{}{"accessToken": "baz"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name":"foo","password":"***","passwordHint":"foo","tokens":[{"accessToken":"***"}],"user_password":"***"}`
+	if got := l.payload(msg); got != want {
+		t.Errorf("expect %s, got %s", want, got)
+	}
+	if got := l.payload(map[string]string{"Password": "bar"}); got != `{"Password":"***"}` {
+		t.Errorf("expect redacted, got %s", got)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	l := NewLogger(Conf{SampleRate: 0, SlowThreshold: time.Second})
+	tests := []struct {
+		name    string
+		code    codes.Code
+		latency time.Duration
+		dropped bool
+	}{
+		{name: "ok", code: codes.OK, dropped: true},
+		{name: "slow", code: codes.OK, latency: time.Second * 2},
+		{name: "client error", code: codes.InvalidArgument},
+		{name: "not found", code: codes.NotFound},
+		{name: "server error", code: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slow := tt.latency > l.conf.SlowThreshold
+			if got := l.sampledOut(Entry{Code: tt.code, Latency: tt.latency}, slow); got != tt.dropped {
+				t.Errorf("expect %v, got %v", tt.dropped, got)
+			}
+		})
+	}
+}
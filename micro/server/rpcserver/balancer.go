@@ -7,6 +7,9 @@ import (
 
 	"mymicro/micro/registry"
 	"mymicro/micro/server/rpcserver/selector"
+	"mymicro/micro/server/rpcserver/selector/p2c"
+	"mymicro/micro/server/rpcserver/selector/random"
+	"mymicro/micro/server/rpcserver/selector/wrr"
 )
 
 const (
@@ -14,21 +17,59 @@ const (
 )
 
 var (
+	_ balancer.Builder   = &builder{}
 	_ base.PickerBuilder = &balancerBuilder{}
 	_ balancer.Picker    = &balancerPicker{}
 )
 
+func init() {
+	RegisterBalancer(p2c.Name, p2c.NewBuilder())
+	RegisterBalancer(wrr.Name, wrr.NewBuilder())
+	RegisterBalancer(random.Name, random.NewBuilder())
+}
+
+// InitBuilder registers the global selector as the "selector" balancer,
+// p2c is used when no global selector has been set.
 func InitBuilder() {
-	b := base.NewBalancerBuilder(
-		balancerName,
-		&balancerBuilder{builder: selector.GlobalSelector()},
-		base.Config{HealthCheck: true},
-	)
-	balancer.Register(b)
+	b := selector.GlobalSelector()
+	if b == nil {
+		b = p2c.NewBuilder()
+	}
+	RegisterBalancer(balancerName, b)
+}
+
+// RegisterBalancer registers a selector builder as a named gRPC balancer,
+// which can be used by WithBalancerName(name).
+func RegisterBalancer(name string, b selector.Builder) {
+	balancer.Register(&builder{
+		name:     name,
+		selector: b,
+		config:   base.Config{HealthCheck: true},
+	})
+}
+
+// builder creates one selector per ClientConn, so node statistics are kept
+// across picker rebuilds.
+type builder struct {
+	name     string
+	selector selector.Builder
+	config   base.Config
+}
+
+func (b *builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &balancerBuilder{selector: b.selector.Build()}
+	return &selectorBalancer{
+		Balancer: base.NewBalancerBuilder(b.name, pb, b.config).Build(cc, opts),
+		id:       registerSelector(opts.Target.String(), b.name, pb.selector),
+	}
+}
+
+func (b *builder) Name() string {
+	return b.name
 }
 
 type balancerBuilder struct {
-	builder selector.Builder
+	selector selector.Selector
 }
 
 func (b *balancerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
@@ -36,22 +77,27 @@ func (b *balancerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
 		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
 	}
 	nodes := make([]selector.Node, 0, len(info.ReadySCs))
+	subConns := make(map[string]balancer.SubConn, len(info.ReadySCs))
 	for conn, info := range info.ReadySCs {
 		ins, _ := info.Address.Attributes.Value("rawServiceInstance").(*registry.ServiceInstance)
 		nodes = append(nodes, &grpcNode{
 			Node:    selector.NewNode("grpc", info.Address.Addr, ins),
 			subConn: conn,
 		})
+		subConns[info.Address.Addr] = conn
 	}
-	p := &balancerPicker{
-		selector: b.builder.Build(),
+	b.selector.Apply(nodes)
+	return &balancerPicker{
+		selector: b.selector,
+		subConns: subConns,
 	}
-	p.selector.Apply(nodes)
-	return p
 }
 
 type balancerPicker struct {
 	selector selector.Selector
+	// subConns maps node address to the ready SubConn when the picker is built,
+	// nodes kept by the selector may reference an outdated SubConn.
+	subConns map[string]balancer.SubConn
 }
 
 func (p *balancerPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
@@ -59,8 +105,12 @@ func (p *balancerPicker) Pick(info balancer.PickInfo) (balancer.PickResult, erro
 	if err != nil {
 		return balancer.PickResult{}, err
 	}
+	sc, ok := p.subConns[n.Address()]
+	if !ok {
+		sc = n.(*grpcNode).subConn
+	}
 	return balancer.PickResult{
-		SubConn: n.(*grpcNode).subConn,
+		SubConn: sc,
 		Done: func(di balancer.DoneInfo) {
 			done(info.Ctx, selector.DoneInfo{
 				Err:           di.Err,
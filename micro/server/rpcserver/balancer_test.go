@@ -0,0 +1,102 @@
+package rpcserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"mymicro/micro/server/rpcserver/selector"
+	"mymicro/micro/server/rpcserver/selector/p2c"
+	"mymicro/micro/server/rpcserver/selector/random"
+	"mymicro/micro/server/rpcserver/selector/wrr"
+)
+
+func TestNamedBalancer(t *testing.T) {
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		srv := NewServer(WithAddress("127.0.0.1:0"))
+		go func() {
+			_ = srv.Start(context.Background())
+		}()
+		defer srv.Stop(context.Background())
+		addrs = append(addrs, srv.lis.Addr().String())
+	}
+
+	for _, name := range []string{p2c.Name, wrr.Name, random.Name} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		conn, err := DailInsecure(ctx,
+			WithEndpoint("direct:///"+strings.Join(addrs, ",")),
+			WithBalancerName(name),
+		)
+		if err != nil {
+			cancel()
+			t.Fatalf("dial with balancer %s: %v", name, err)
+		}
+		p := &selector.Peer{}
+		_, err = grpc_health_v1.NewHealthClient(conn).Check(selector.NewPeerContext(ctx, p),
+			&grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+		if err != nil {
+			t.Errorf("balancer %s: %v", name, err)
+		}
+		if p.Node == nil {
+			t.Errorf("balancer %s: expect peer node, got nil", name)
+		} else if p.Node.Address() != addrs[0] && p.Node.Address() != addrs[1] {
+			t.Errorf("balancer %s: unexpected peer address %s", name, p.Node.Address())
+		}
+		var found bool
+		for _, s := range SelectorSnapshots() {
+			if s.Balancer == name && len(s.Nodes) == 2 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("balancer %s: expect selector snapshot with 2 nodes", name)
+		}
+		_ = conn.Close()
+		cancel()
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	var (
+		addrs []string
+		srvs  []*Server
+	)
+	for i := 0; i < 2; i++ {
+		srv := NewServer(WithAddress("127.0.0.1:0"))
+		go func() {
+			_ = srv.Start(context.Background())
+		}()
+		defer srv.Stop(context.Background())
+		addrs = append(addrs, srv.lis.Addr().String())
+		srvs = append(srvs, srv)
+	}
+	time.Sleep(time.Millisecond * 50)
+	srvs[1].health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := DailInsecure(ctx,
+		WithEndpoint("direct:///"+strings.Join(addrs, ",")),
+		WithHealthCheck(true),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	for i := 0; i < 10; i++ {
+		p := &selector.Peer{}
+		_, err = grpc_health_v1.NewHealthClient(conn).Check(selector.NewPeerContext(ctx, p),
+			&grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		if p.Node.Address() != addrs[0] {
+			t.Errorf("expect healthy node %s, got %s", addrs[0], p.Node.Address())
+		}
+	}
+}
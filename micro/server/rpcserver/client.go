@@ -2,15 +2,22 @@ package rpcserver
 
 import (
 	"context"
+	"encoding/json"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"time"
 
 	"google.golang.org/grpc"
 	grpcInsecure "google.golang.org/grpc/credentials/insecure"
+	// register the client-side health check function
+	_ "google.golang.org/grpc/health"
 
+	"mymicro/micro/core/fault"
 	"mymicro/micro/registry"
+	"mymicro/micro/server/rpcserver/accesslog"
 	"mymicro/micro/server/rpcserver/clientinterceptors"
+	_ "mymicro/micro/server/rpcserver/resolver/direct"
 	"mymicro/micro/server/rpcserver/resolver/discovery"
+	"mymicro/micro/server/rpcserver/selector/p2c"
 	"mymicro/pkg/log"
 )
 
@@ -18,8 +25,9 @@ type ClientOption func(o *clientOptions)
 
 type clientOptions struct {
 	endpoint           string
-	timeout            time.Duration
+	timeout            clientinterceptors.TimeoutConf
 	discovery          registry.Discovery
+	resolverOpts       []discovery.Option
 	unaryInterceptors  []grpc.UnaryClientInterceptor
 	streamInterceptors []grpc.StreamClientInterceptor
 	rpcOpts            []grpc.DialOption
@@ -27,6 +35,20 @@ type clientOptions struct {
 	logger             log.LogHelper
 	enableTracing      bool
 	enableMetrics      bool
+	enableErrorCodes   bool
+	caller             string
+	accessLog          *accesslog.Conf
+	healthCheck        bool
+	healthCheckService string
+	retry              *clientinterceptors.RetryConf
+	retryOpts          []clientinterceptors.RetryOption
+	hedgingRatio       float64
+	hedgingMethods     []clientinterceptors.MethodHedgingConf
+	singleflight       []clientinterceptors.MethodSingleflightConf
+	breaker            bool
+	breakerOpts        []clientinterceptors.BreakerOption
+	tls                tlsFiles
+	faultInjector      *fault.Injector
 }
 
 func WithEnableTracing(enable bool) ClientOption {
@@ -41,6 +63,114 @@ func WithEnableMetrics(enable bool) ClientOption {
 	}
 }
 
+// WithEnableErrorCodes enables rebuilding the errors with code of pkg/errors from status errors, enabled by default.
+func WithEnableErrorCodes(enable bool) ClientOption {
+	return func(o *clientOptions) {
+		o.enableErrorCodes = enable
+	}
+}
+
+// WithCaller sets the name of the calling service sent in metadata.
+func WithCaller(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.caller = name
+	}
+}
+
+// WithClientAccessLog enables the access log of each call, e.g. accesslog.DefaultConf().
+func WithClientAccessLog(conf accesslog.Conf) ClientOption {
+	return func(o *clientOptions) {
+		o.accessLog = &conf
+	}
+}
+
+// WithToken attaches the bearer token of source to each call,
+// the token of the inbound request is forwarded instead if forwardInbound and present.
+func WithToken(source TokenSource, forwardInbound bool) ClientOption {
+	return func(o *clientOptions) {
+		o.rpcOpts = append(o.rpcOpts, grpc.WithPerRPCCredentials(NewTokenCredentials(source, forwardInbound)))
+	}
+}
+
+// WithHealthCheck enables client-side health checking of each subchannel through grpc_health_v1,
+// subchannels which are not SERVING are excluded from the balancer.
+func WithHealthCheck(enable bool) ClientOption {
+	return func(o *clientOptions) {
+		o.healthCheck = enable
+	}
+}
+
+// WithHealthCheckServiceName sets the service name used by client-side health checking,
+// empty means the overall health of the server.
+func WithHealthCheckServiceName(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.healthCheckService = name
+	}
+}
+
+// WithRetry enables retrying failed unary calls, the retries share the call timeout.
+func WithRetry(conf clientinterceptors.RetryConf, opts ...clientinterceptors.RetryOption) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = &conf
+		o.retryOpts = opts
+	}
+}
+
+// WithHedging enables hedged requests of the given idempotent methods,
+// budgetRatio caps the hedges to the ratio of requests.
+func WithHedging(budgetRatio float64, methods ...clientinterceptors.MethodHedgingConf) ClientOption {
+	return func(o *clientOptions) {
+		o.hedgingRatio = budgetRatio
+		o.hedgingMethods = methods
+	}
+}
+
+// WithSingleflight coalesces the identical in-flight calls of the given idempotent read methods into one call,
+// the coalesced calls are not counted by the tracing, metrics and access log of the client.
+func WithSingleflight(methods ...clientinterceptors.MethodSingleflightConf) ClientOption {
+	return func(o *clientOptions) {
+		o.singleflight = append(o.singleflight, methods...)
+	}
+}
+
+// WithBreaker enables the circuit breaker of each target service and method.
+func WithBreaker(opts ...clientinterceptors.BreakerOption) ClientOption {
+	return func(o *clientOptions) {
+		o.breaker = true
+		o.breakerOpts = opts
+	}
+}
+
+// WithRootCA verifies the server certificate by the CA file instead of the system roots with Dail,
+// the file is reloaded on change.
+func WithRootCA(caFile string) ClientOption {
+	return func(o *clientOptions) {
+		o.tls.caFile = caFile
+	}
+}
+
+// WithClientCert sends the client certificate for mutual TLS with Dail, the files are reloaded on change.
+func WithClientCert(certFile, keyFile string) ClientOption {
+	return func(o *clientOptions) {
+		o.tls.certFile, o.tls.keyFile = certFile, keyFile
+	}
+}
+
+// WithServerName overrides the server name verified with Dail, the host of the endpoint by default.
+func WithServerName(serverName string) ClientOption {
+	return func(o *clientOptions) {
+		o.tls.serverName = serverName
+	}
+}
+
+// WithClientFaultInjector injects the faults into the calls if the injector is enabled,
+// the faults are injected after the other interceptors so that retry and breaker see the failures.
+func WithClientFaultInjector(injector *fault.Injector) ClientOption {
+	return func(o *clientOptions) {
+		o.faultInjector = injector
+	}
+}
+
 func WithEndpoint(endpoint string) ClientOption {
 	return func(o *clientOptions) {
 		o.endpoint = endpoint
@@ -49,7 +179,29 @@ func WithEndpoint(endpoint string) ClientOption {
 
 func WithClientTimeout(timeout time.Duration) ClientOption {
 	return func(o *clientOptions) {
-		o.timeout = timeout
+		o.timeout.Timeout = timeout
+	}
+}
+
+// WithMethodTimeouts overrides the client timeout for the methods.
+func WithMethodTimeouts(methods ...clientinterceptors.MethodTimeoutConf) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout.Methods = append(o.timeout.Methods, methods...)
+	}
+}
+
+// withTimeoutBudget sets the deadline budget of the calls and keeps the client timeout.
+func withTimeoutBudget(conf clientinterceptors.TimeoutConf) ClientOption {
+	return func(o *clientOptions) {
+		conf.Timeout = o.timeout.Timeout
+		o.timeout = conf
+	}
+}
+
+// WithTimeoutConf sets the timeouts and the deadline budget of the calls, it replaces the client timeout.
+func WithTimeoutConf(conf clientinterceptors.TimeoutConf) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = conf
 	}
 }
 
@@ -59,6 +211,13 @@ func WithDiscovery(discovery registry.Discovery) ClientOption {
 	}
 }
 
+// WithResolverOptions sets the options of the discovery resolver, e.g. debounce and empty policy.
+func WithResolverOptions(opts ...discovery.Option) ClientOption {
+	return func(o *clientOptions) {
+		o.resolverOpts = opts
+	}
+}
+
 func WithClientUnaryInterceptor(in ...grpc.UnaryClientInterceptor) ClientOption {
 	return func(o *clientOptions) {
 		o.unaryInterceptors = in
@@ -71,9 +230,10 @@ func WithClientStreamInterceptor(in ...grpc.StreamClientInterceptor) ClientOptio
 	}
 }
 
+// WithClientOptions appends the grpc.DialOption, they are kept together with the options set by WithToken.
 func WithClientOptions(opts ...grpc.DialOption) ClientOption {
 	return func(o *clientOptions) {
-		o.rpcOpts = opts
+		o.rpcOpts = append(o.rpcOpts, opts...)
 	}
 }
 
@@ -92,47 +252,98 @@ func Dail(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, error) {
 
 func dail(ctx context.Context, insecure bool, opts ...ClientOption) (*grpc.ClientConn, error) {
 	options := clientOptions{
-		timeout:       2000 * time.Millisecond,
-		balancerName:  "round_robin",
-		enableTracing: true,
+		timeout:          clientinterceptors.TimeoutConf{Timeout: 2000 * time.Millisecond},
+		balancerName:     p2c.Name,
+		enableTracing:    true,
+		enableErrorCodes: true,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
 
 	// TODO 客户端默认拦截器
-	ints := []grpc.UnaryClientInterceptor{
-		clientinterceptors.TimeoutInterceptor(options.timeout),
+	var ints []grpc.UnaryClientInterceptor
+	// 错误转换放在最外层，内层拦截器仍然按status code处理
+	if options.enableErrorCodes {
+		ints = append(ints, clientinterceptors.ErrorInterceptor())
+	}
+	if options.caller != "" {
+		ints = append(ints, clientinterceptors.CallerInterceptor(options.caller))
+	}
+	ints = append(ints, clientinterceptors.TimeoutInterceptor(options.timeout))
+	if len(options.singleflight) > 0 {
+		ints = append(ints, clientinterceptors.SingleflightInterceptor(options.singleflight...))
 	}
 	if options.enableTracing {
 		ints = append(ints, otelgrpc.UnaryClientInterceptor())
 	}
+	if options.accessLog != nil {
+		ints = append(ints, clientinterceptors.LoggingInterceptor(*options.accessLog))
+	}
 	if options.enableMetrics {
 		ints = append(ints, clientinterceptors.PrometheusInterceptor())
 	}
+	// 熔断在重试之外，熔断打开时直接失败，不会被重试消耗重试预算
+	if options.breaker {
+		ints = append(ints, clientinterceptors.BreakerInterceptor(options.breakerOpts...))
+	}
+	if options.retry != nil {
+		ints = append(ints, clientinterceptors.RetryInterceptor(*options.retry, options.retryOpts...))
+	}
+	if len(options.hedgingMethods) > 0 {
+		ints = append(ints, clientinterceptors.HedgingInterceptor(options.hedgingRatio, options.hedgingMethods...))
+	}
 	var streamInts []grpc.StreamClientInterceptor
+	if options.enableErrorCodes {
+		streamInts = append(streamInts, clientinterceptors.StreamErrorInterceptor())
+	}
+	if options.caller != "" {
+		streamInts = append(streamInts, clientinterceptors.StreamCallerInterceptor(options.caller))
+	}
+	streamInts = append(streamInts, clientinterceptors.StreamTimeoutInterceptor(options.timeout))
+	if options.enableTracing {
+		streamInts = append(streamInts, otelgrpc.StreamClientInterceptor())
+	}
+	if options.accessLog != nil {
+		streamInts = append(streamInts, clientinterceptors.StreamLoggingInterceptor(*options.accessLog))
+	}
+	if options.enableMetrics {
+		streamInts = append(streamInts, clientinterceptors.StreamPrometheusInterceptor())
+	}
 	if len(options.unaryInterceptors) > 0 {
 		ints = append(ints, options.unaryInterceptors...)
 	}
 	if len(options.streamInterceptors) > 0 {
 		streamInts = append(streamInts, options.streamInterceptors...)
 	}
+	// 故障注入最靠近网络，重试、熔断和用户拦截器都能观察到注入的故障
+	if options.faultInjector.Enabled() {
+		ints = append(ints, clientinterceptors.FaultInterceptor(options.faultInjector))
+		streamInts = append(streamInts, clientinterceptors.StreamFaultInterceptor(options.faultInjector))
+	}
 
 	grpcOpts := []grpc.DialOption{
-		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy": "` + options.balancerName + `"}`),
+		grpc.WithDefaultServiceConfig(serviceConfig(&options)),
 		grpc.WithChainUnaryInterceptor(ints...),
 		grpc.WithChainStreamInterceptor(streamInts...),
 	}
 
 	// 服务发现选项
 	if options.discovery != nil {
+		resolverOpts := append([]discovery.Option{discovery.WithInsecure(insecure)}, options.resolverOpts...)
 		grpcOpts = append(grpcOpts, grpc.WithResolvers(
-			discovery.NewBuilder(options.discovery, discovery.WithInsecure(insecure))),
+			discovery.NewBuilder(options.discovery, resolverOpts...)),
 		)
 	}
 
 	if insecure {
 		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(grpcInsecure.NewCredentials()))
+	} else {
+		creds, err := clientTLSCredentials(options.tls)
+		if err != nil {
+			return nil, err
+		}
+		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(creds))
 	}
 
 	if len(options.rpcOpts) > 0 {
@@ -141,6 +352,25 @@ func dail(ctx context.Context, insecure bool, opts ...ClientOption) (*grpc.Clien
 	return grpc.DialContext(ctx, options.endpoint, grpcOpts...)
 }
 
+type healthCheckConfig struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// serviceConfig builds the default service config json of the client connection.
+func serviceConfig(o *clientOptions) string {
+	sc := struct {
+		LoadBalancingPolicy string             `json:"loadBalancingPolicy"`
+		HealthCheckConfig   *healthCheckConfig `json:"healthCheckConfig,omitempty"`
+	}{
+		LoadBalancingPolicy: o.balancerName,
+	}
+	if o.healthCheck {
+		sc.HealthCheckConfig = &healthCheckConfig{ServiceName: o.healthCheckService}
+	}
+	b, _ := json.Marshal(sc)
+	return string(b)
+}
+
 //func WithLogger(log *log.Logger) ClientOption {
 //	return func(o *clientOptions) {
 //		o.logger = log
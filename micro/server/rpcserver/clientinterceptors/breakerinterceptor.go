@@ -0,0 +1,134 @@
+package clientinterceptors
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/breaker"
+	"mymicro/micro/core/metric"
+)
+
+var metricBreakerState = metric.NewGaugeVec(&metric.GaugeVecOpts{
+	Namespace: clientNamespace,
+	Subsystem: "breaker",
+	Name:      "xhy_state",
+	Help:      "rpc client breaker state, 0 closed, 1 half-open, 2 open.",
+	Labels:    []string{"target", "method"},
+})
+
+type (
+	// BreakerFallback is called instead of the rejected call when the breaker is open,
+	// its error is returned to the caller.
+	BreakerFallback func(ctx context.Context, method string, req, reply interface{}, err error) error
+
+	// BreakerOption is breaker interceptor option.
+	BreakerOption func(o *breakerOptions)
+
+	breakerOptions struct {
+		codes     map[codes.Code]struct{}
+		fallbacks map[string]BreakerFallback
+		opts      []breaker.Option
+	}
+
+	breakers struct {
+		mu sync.Mutex
+		m  map[string]*breaker.Breaker
+	}
+)
+
+// WithBreakerCodes sets the status codes counted as failures,
+// codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted,
+// codes.DataLoss and codes.Unknown by default.
+func WithBreakerCodes(cs ...codes.Code) BreakerOption {
+	return func(o *breakerOptions) {
+		o.codes = make(map[codes.Code]struct{}, len(cs))
+		for _, c := range cs {
+			o.codes[c] = struct{}{}
+		}
+	}
+}
+
+// WithBreakerFallback registers the fallback of the method, "*" registers the default one.
+func WithBreakerFallback(fullMethod string, fallback BreakerFallback) BreakerOption {
+	return func(o *breakerOptions) {
+		o.fallbacks[fullMethod] = fallback
+	}
+}
+
+// WithBreakerOptions sets the options of each breaker.
+func WithBreakerOptions(opts ...breaker.Option) BreakerOption {
+	return func(o *breakerOptions) {
+		o.opts = append(o.opts, opts...)
+	}
+}
+
+// BreakerInterceptor returns a func that rejects the calls with codes.Unavailable
+// while the breaker of the target service and method is open.
+func BreakerInterceptor(opts ...BreakerOption) grpc.UnaryClientInterceptor {
+	o := breakerOptions{
+		codes: map[codes.Code]struct{}{
+			codes.Unavailable:       {},
+			codes.DeadlineExceeded:  {},
+			codes.Internal:          {},
+			codes.ResourceExhausted: {},
+			codes.DataLoss:          {},
+			codes.Unknown:           {},
+		},
+		fallbacks: make(map[string]BreakerFallback),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	bs := &breakers{m: make(map[string]*breaker.Breaker)}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var target string
+		if cc != nil {
+			target = cc.Target()
+		}
+		b := bs.get(target, method, o.opts)
+		if err := b.Allow(); err != nil {
+			err = status.Error(codes.Unavailable, err.Error())
+			if fallback := o.fallback(method); fallback != nil {
+				return fallback(ctx, method, req, reply, err)
+			}
+			return err
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if _, ok := o.codes[status.Code(err)]; ok {
+			b.MarkFailed()
+		} else {
+			b.MarkSuccess()
+		}
+		return err
+	}
+}
+
+func (o *breakerOptions) fallback(method string) BreakerFallback {
+	if fallback, ok := o.fallbacks[method]; ok {
+		return fallback
+	}
+	return o.fallbacks["*"]
+}
+
+func (bs *breakers) get(target, method string, opts []breaker.Option) *breaker.Breaker {
+	name := target + method
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	b, ok := bs.m[name]
+	if !ok {
+		opts = append(append([]breaker.Option(nil), opts...), breaker.WithStateChange(
+			func(name string, from, to breaker.State) {
+				metricBreakerState.Set(float64(to), target, method)
+			}))
+		b = breaker.New(name, opts...)
+		bs.m[name] = b
+		metricBreakerState.Set(float64(breaker.StateClosed), target, method)
+	}
+	return b
+}
@@ -0,0 +1,58 @@
+package clientinterceptors
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/breaker"
+)
+
+func TestBreakerInterceptor(t *testing.T) {
+	var fallbacks, opened int
+	interceptor := BreakerInterceptor(
+		WithBreakerOptions(breaker.WithMinRequests(2), breaker.WithStateChange(func(_ string, _, to breaker.State) {
+			if to == breaker.StateOpen {
+				opened++
+			}
+		})),
+		WithBreakerFallback("/bar", func(ctx context.Context, method string, req, reply interface{}, err error) error {
+			fallbacks++
+			return nil
+		}),
+	)
+
+	for _, method := range []string{"/foo", "/bar"} {
+		var calls int
+		invoker := failingInvoker(&calls, 10, codes.Unavailable)
+		for i := 0; i < 4; i++ {
+			_ = interceptor(context.Background(), method, nil, nil, nil, invoker)
+		}
+		if calls != 2 {
+			t.Errorf("expect 2 calls, got %d", calls)
+		}
+	}
+	err := interceptor(context.Background(), "/foo", nil, nil, nil, failingInvoker(new(int), 0, codes.OK))
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expect %v, got %v", codes.Unavailable, err)
+	}
+	if fallbacks != 2 {
+		t.Errorf("expect 2 fallbacks, got %d", fallbacks)
+	}
+	// the state change callback of the user is kept with the one of the metrics
+	if opened != 2 {
+		t.Errorf("expect 2 breakers opened, got %d", opened)
+	}
+
+	// client errors don't trip the breaker
+	var calls int
+	invoker := failingInvoker(&calls, 10, codes.InvalidArgument)
+	for i := 0; i < 4; i++ {
+		_ = interceptor(context.Background(), "/baz", nil, nil, nil, invoker)
+	}
+	if calls != 4 {
+		t.Errorf("expect 4 calls, got %d", calls)
+	}
+}
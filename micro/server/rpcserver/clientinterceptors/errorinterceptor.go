@@ -0,0 +1,49 @@
+package clientinterceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"mymicro/micro/server/rpcserver/rpcerrors"
+)
+
+// ErrorInterceptor returns a func that rebuilds the errors with code of pkg/errors from status errors,
+// so that errors.IsCode and errors.ParseCoder work across services.
+func ErrorInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return rpcerrors.FromStatus(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamErrorInterceptor returns a func that rebuilds the errors of streams with code of pkg/errors
+// from status errors, io.EOF is kept as it is.
+func StreamErrorInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, rpcerrors.FromStatus(err)
+		}
+		return &errorClientStream{ClientStream: stream}, nil
+	}
+}
+
+type errorClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorClientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	return md, rpcerrors.FromStatus(err)
+}
+
+func (s *errorClientStream) SendMsg(m interface{}) error {
+	return rpcerrors.FromStatus(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorClientStream) RecvMsg(m interface{}) error {
+	return rpcerrors.FromStatus(s.ClientStream.RecvMsg(m))
+}
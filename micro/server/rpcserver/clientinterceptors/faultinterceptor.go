@@ -0,0 +1,70 @@
+package clientinterceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/fault"
+	"mymicro/micro/core/metric"
+)
+
+var metricClientFaultTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: clientNamespace,
+	Subsystem: "requests",
+	Name:      "xhy_fault_total",
+	Help:      "rpc client requests fault injected count.",
+	Labels:    []string{"method"},
+})
+
+// FaultInterceptor returns a func that injects the faults of the injector into unary calls before sending them,
+// the rules are keyed by the full method, the CallerKey and the outgoing metadata.
+// Abort fails the call with codes.Unavailable without sending it, like a broken connection.
+func FaultInterceptor(injector *fault.Injector) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := injectFault(ctx, injector, method); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamFaultInterceptor returns a func that injects the faults of the injector into streams before creating them.
+func StreamFaultInterceptor(injector *fault.Injector) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := injectFault(ctx, injector, method); err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func injectFault(ctx context.Context, injector *fault.Injector, method string) error {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	header := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	rule, ok := injector.Fire(method, header(CallerKey), header)
+	if !ok {
+		return nil
+	}
+	metricClientFaultTotal.Inc(method)
+	if err := injector.Wait(ctx, rule); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	switch {
+	case rule.Abort:
+		return status.Error(codes.Unavailable, rule.Error())
+	case rule.Code != 0:
+		return status.Error(codes.Code(rule.Code), rule.Error())
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package clientinterceptors
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"mymicro/micro/core/metric"
+	"mymicro/micro/server/rpcserver/selector"
+)
+
+const (
+	// defaultHedgingDelay is used before enough latency samples are collected.
+	defaultHedgingDelay = 100 * time.Millisecond
+	// hedgingMinSamples is the number of samples required to use the observed p95.
+	hedgingMinSamples = 20
+	hedgingWindowSize = 128
+	// hedgingBudgetMaxTokens caps the burst of hedges.
+	hedgingBudgetMaxTokens = 10
+)
+
+var (
+	metricHedgeTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_hedge_total",
+		Help:      "rpc client requests hedge count.",
+		Labels:    []string{"method"},
+	})
+
+	metricHedgeWinTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_hedge_win_total",
+		Help:      "rpc client requests won by hedge count.",
+		Labels:    []string{"method"},
+	})
+)
+
+type (
+	// MethodHedgingConf defines the hedging policy of an idempotent gRPC method.
+	MethodHedgingConf struct {
+		FullMethod string
+		// MaxAttempts is the max number of attempts including the first one, 2 if not set.
+		MaxAttempts int
+		// Delay is the delay before sending a hedge, zero uses the observed p95 latency of the attempts of the method.
+		Delay time.Duration
+	}
+
+	hedgingMethod struct {
+		conf    MethodHedgingConf
+		latency *latencyWindow
+	}
+
+	hedgeResult struct {
+		reply   proto.Message
+		err     error
+		attempt int
+		peer    *selector.Peer
+		out     *callOutputs
+	}
+
+	// callOutputs are the values written by the grpc.Peer, grpc.Header and grpc.Trailer call options,
+	// each attempt writes its own ones and only those of the winner are copied to the caller.
+	callOutputs struct {
+		peer    *peer.Peer
+		header  *metadata.MD
+		trailer *metadata.MD
+	}
+
+	// latencyWindow keeps the latest latencies of a method.
+	latencyWindow struct {
+		mu      sync.Mutex
+		samples [hedgingWindowSize]time.Duration
+		idx     int
+		count   int
+		p95     time.Duration
+	}
+
+	// hedgeBudget allows at most ratio of the requests to be hedged.
+	hedgeBudget struct {
+		mu     sync.Mutex
+		tokens float64
+		ratio  float64
+	}
+)
+
+// HedgingInterceptor returns a func that sends hedged attempts of the given methods to different nodes,
+// the first successful response is used and the others are canceled.
+// The methods must be idempotent, the grpc.Peer, grpc.Header and grpc.Trailer call options get the values
+// of the winning attempt.
+// budgetRatio caps the hedges to the ratio of requests, 0.1 if <= 0.
+func HedgingInterceptor(budgetRatio float64, methods ...MethodHedgingConf) grpc.UnaryClientInterceptor {
+	if budgetRatio <= 0 {
+		budgetRatio = 0.1
+	}
+	hedged := make(map[string]*hedgingMethod, len(methods))
+	for _, m := range methods {
+		if m.FullMethod == "" {
+			continue
+		}
+		if m.MaxAttempts < 2 {
+			m.MaxAttempts = 2
+		}
+		hedged[m.FullMethod] = &hedgingMethod{conf: m, latency: &latencyWindow{}}
+	}
+	budget := &hedgeBudget{ratio: budgetRatio}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		m, ok := hedged[method]
+		msg, isProto := reply.(proto.Message)
+		if !ok || !isProto {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		budget.deposit()
+		return m.invoke(ctx, budget, method, req, msg, cc, invoker, opts...)
+	}
+}
+
+func (m *hedgingMethod) invoke(ctx context.Context, budget *hedgeBudget, method string, req interface{},
+	reply proto.Message, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// exclude the nodes picked by previous attempts
+	var mu sync.Mutex
+	picked := make(map[string]struct{})
+	ctx = selector.NewFilterContext(ctx, func(_ context.Context, nodes []selector.Node) []selector.Node {
+		mu.Lock()
+		defer mu.Unlock()
+		candidates := make([]selector.Node, 0, len(nodes))
+		for _, n := range nodes {
+			if _, ok := picked[n.Address()]; !ok {
+				candidates = append(candidates, n)
+			}
+		}
+		if len(candidates) == 0 {
+			return nodes
+		}
+		return candidates
+	})
+	ctx = selector.NewPickCallbackContext(ctx, func(n selector.Node) {
+		mu.Lock()
+		picked[n.Address()] = struct{}{}
+		mu.Unlock()
+	})
+
+	// 每次尝试使用自己的Peer，结束后把胜出尝试的节点写回调用方的Peer
+	callerPeer, hasPeer := selector.FromPeerContext(ctx)
+	// 并发的尝试不能写同一个调用选项的输出，被取消的尝试可能在返回后才结束
+	opts, callerOut := splitCallOutputs(opts)
+	results := make(chan hedgeResult, m.conf.MaxAttempts)
+	starts := make([]time.Time, m.conf.MaxAttempts)
+	finished := make([]bool, m.conf.MaxAttempts)
+	call := func(attempt int) {
+		p := &selector.Peer{}
+		out := callerOut.attempt()
+		r := reply.ProtoReflect().New().Interface()
+		err := invoker(selector.NewPeerContext(ctx, p), method, req, r, cc, append(opts[:len(opts):len(opts)], out.callOptions()...)...)
+		results <- hedgeResult{reply: r, err: err, attempt: attempt, peer: p, out: out}
+	}
+	launch := func(attempt int) {
+		starts[attempt] = time.Now()
+		go call(attempt)
+	}
+
+	delay := m.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	launch(0)
+	launched, pending := 1, 1
+	var lastErr error
+	for {
+		select {
+		case res := <-results:
+			pending--
+			finished[res.attempt] = true
+			// 每次尝试从自己的开始时间计算耗时，失败的尝试同样计入
+			m.latency.add(time.Since(starts[res.attempt]))
+			if res.err == nil {
+				proto.Reset(reply)
+				proto.Merge(reply, res.reply)
+				if hasPeer {
+					callerPeer.Node = res.peer.Node
+				}
+				callerOut.copyFrom(res.out)
+				// the canceled attempts take at least the time elapsed so far
+				for i := 0; i < launched; i++ {
+					if !finished[i] {
+						m.latency.add(time.Since(starts[i]))
+					}
+				}
+				if res.attempt > 0 {
+					metricHedgeWinTotal.Inc(method)
+				}
+				return nil
+			}
+			lastErr = res.err
+			if pending == 0 {
+				return lastErr
+			}
+		case <-timer.C:
+			if launched < m.conf.MaxAttempts && budget.withdraw() {
+				metricHedgeTotal.Inc(method)
+				launch(launched)
+				launched++
+				pending++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// splitCallOutputs removes the grpc.Peer, grpc.Header and grpc.Trailer call options from opts
+// and returns the outputs of them.
+func splitCallOutputs(opts []grpc.CallOption) ([]grpc.CallOption, *callOutputs) {
+	out := &callOutputs{}
+	rest := make([]grpc.CallOption, 0, len(opts))
+	for _, o := range opts {
+		switch o := o.(type) {
+		case grpc.PeerCallOption:
+			out.peer = o.PeerAddr
+		case grpc.HeaderCallOption:
+			out.header = o.HeaderAddr
+		case grpc.TrailerCallOption:
+			out.trailer = o.TrailerAddr
+		default:
+			rest = append(rest, o)
+		}
+	}
+	return rest, out
+}
+
+// attempt returns the outputs of an attempt, only the outputs requested by the caller are set.
+func (o *callOutputs) attempt() *callOutputs {
+	a := &callOutputs{}
+	if o.peer != nil {
+		a.peer = &peer.Peer{}
+	}
+	if o.header != nil {
+		a.header = &metadata.MD{}
+	}
+	if o.trailer != nil {
+		a.trailer = &metadata.MD{}
+	}
+	return a
+}
+
+func (o *callOutputs) callOptions() []grpc.CallOption {
+	var opts []grpc.CallOption
+	if o.peer != nil {
+		opts = append(opts, grpc.Peer(o.peer))
+	}
+	if o.header != nil {
+		opts = append(opts, grpc.Header(o.header))
+	}
+	if o.trailer != nil {
+		opts = append(opts, grpc.Trailer(o.trailer))
+	}
+	return opts
+}
+
+// copyFrom copies the outputs of the winning attempt to the caller.
+func (o *callOutputs) copyFrom(a *callOutputs) {
+	if o.peer != nil {
+		*o.peer = *a.peer
+	}
+	if o.header != nil {
+		*o.header = *a.header
+	}
+	if o.trailer != nil {
+		*o.trailer = *a.trailer
+	}
+}
+
+func (m *hedgingMethod) delay() time.Duration {
+	if m.conf.Delay > 0 {
+		return m.conf.Delay
+	}
+	if p95 := m.latency.percentile95(); p95 > 0 {
+		return p95
+	}
+	return defaultHedgingDelay
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.idx] = d
+	w.idx = (w.idx + 1) % len(w.samples)
+	if w.count < len(w.samples) {
+		w.count++
+	}
+	// recompute every 16 samples to keep add cheap
+	if w.count >= hedgingMinSamples && w.idx%16 == 0 {
+		sorted := make([]time.Duration, w.count)
+		copy(sorted, w.samples[:w.count])
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		w.p95 = sorted[w.count*95/100]
+	}
+}
+
+func (w *latencyWindow) percentile95() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.p95
+}
+
+func (b *hedgeBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > hedgingBudgetMaxTokens {
+		b.tokens = hedgingBudgetMaxTokens
+	}
+}
+
+func (b *hedgeBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,119 @@
+package clientinterceptors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"mymicro/micro/server/rpcserver/selector"
+)
+
+func TestHedgingInterceptor(t *testing.T) {
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		n := atomic.AddInt32(&calls, 1)
+		// the selector writes the picked node to the peer of ctx
+		if p, ok := selector.FromPeerContext(ctx); ok {
+			p.Node = selector.NewNode("grpc", fmt.Sprintf("10.0.0.%d:9000", n), nil)
+		}
+		if n == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		reply.(*wrapperspb.StringValue).Value = "hedge"
+		return nil
+	}
+
+	interceptor := HedgingInterceptor(1, MethodHedgingConf{FullMethod: "/foo", Delay: time.Millisecond * 20})
+	reply := &wrapperspb.StringValue{}
+	p := &selector.Peer{}
+	start := time.Now()
+	if err := interceptor(selector.NewPeerContext(context.Background(), p), "/foo", nil, reply, nil, invoker); err != nil {
+		t.Fatalf("expect nil, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*200 {
+		t.Errorf("expect hedge to win, elapsed %s", elapsed)
+	}
+	if reply.Value != "hedge" {
+		t.Errorf("expect %s, got %s", "hedge", reply.Value)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("expect 2 calls, got %d", calls)
+	}
+	if p.Node == nil || p.Node.Address() != "10.0.0.2:9000" {
+		t.Errorf("expect the node of the winning hedge, got %v", p.Node)
+	}
+}
+
+func TestHedgingCallOutputs(t *testing.T) {
+	var calls int32
+	loserDone := make(chan struct{})
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			// the canceled attempt writes its outputs after the call returns
+			<-ctx.Done()
+			defer close(loserDone)
+		}
+		// grpc writes the outputs of the call options when the call ends
+		for _, o := range opts {
+			switch o := o.(type) {
+			case grpc.PeerCallOption:
+				*o.PeerAddr = peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, byte(n)), Port: 9000}}
+			case grpc.HeaderCallOption:
+				*o.HeaderAddr = metadata.Pairs("attempt", fmt.Sprint(n))
+			}
+		}
+		if n == 1 {
+			return ctx.Err()
+		}
+		reply.(*wrapperspb.StringValue).Value = "hedge"
+		return nil
+	}
+
+	interceptor := HedgingInterceptor(1, MethodHedgingConf{FullMethod: "/foo", Delay: time.Millisecond * 20})
+	var (
+		p      peer.Peer
+		header metadata.MD
+	)
+	err := interceptor(context.Background(), "/foo", nil, &wrapperspb.StringValue{}, nil, invoker,
+		grpc.Peer(&p), grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("expect nil, got %v", err)
+	}
+	if p.Addr == nil || p.Addr.String() != "10.0.0.2:9000" {
+		t.Errorf("expect the peer of the winning hedge, got %v", p.Addr)
+	}
+	if v := header.Get("attempt"); len(v) != 1 || v[0] != "2" {
+		t.Errorf("expect the header of the winning hedge, got %v", v)
+	}
+	<-loserDone
+	if p.Addr.String() != "10.0.0.2:9000" {
+		t.Errorf("expect the peer not overwritten by the canceled attempt, got %v", p.Addr)
+	}
+}
+
+func TestHedgingBudget(t *testing.T) {
+	b := &hedgeBudget{ratio: 0.5}
+	b.deposit()
+	if b.withdraw() {
+		t.Errorf("expect no hedge with 0.5 token")
+	}
+	b.deposit()
+	if !b.withdraw() {
+		t.Errorf("expect hedge with 1 token")
+	}
+}
@@ -0,0 +1,157 @@
+package clientinterceptors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/server/rpcserver/accesslog"
+)
+
+const (
+	// CallerKey is the metadata key of the calling service, the same as serverinterceptors.CallerKey.
+	CallerKey = "x-caller"
+
+	logKind = "client"
+)
+
+// CallerInterceptor returns a func that sends the name of the calling service in CallerKey metadata.
+func CallerInterceptor(name string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withCaller(ctx, name), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamCallerInterceptor returns a func that sends the name of the calling service in CallerKey metadata.
+func StreamCallerInterceptor(name string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withCaller(ctx, name), desc, cc, method, opts...)
+	}
+}
+
+func withCaller(ctx context.Context, name string) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(CallerKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, CallerKey, name)
+}
+
+// LoggingInterceptor returns a func that writes the access logs of unary calls.
+func LoggingInterceptor(conf accesslog.Conf) grpc.UnaryClientInterceptor {
+	logger := accesslog.NewLogger(conf)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var p peer.Peer
+		startTime := time.Now()
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+		e := accesslog.Entry{
+			Kind:    logKind,
+			Method:  method,
+			Caller:  outgoingCaller(ctx),
+			Code:    status.Code(err),
+			Err:     err,
+			Latency: time.Since(startTime),
+			Request: req,
+		}
+		if p.Addr != nil {
+			e.Peer = p.Addr.String()
+		}
+		if err == nil {
+			e.Response = reply
+		}
+		logger.Log(ctx, e)
+		return err
+	}
+}
+
+// StreamLoggingInterceptor returns a func that writes the access logs of streams when they end,
+// i.e. RecvMsg returns an error or io.EOF, or the single response of a client stream is received.
+func StreamLoggingInterceptor(conf accesslog.Conf) grpc.StreamClientInterceptor {
+	logger := accesslog.NewLogger(conf)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s := &loggedClientStream{
+			ctx:           ctx,
+			logger:        logger,
+			method:        method,
+			startTime:     time.Now(),
+			serverStreams: desc.ServerStreams,
+		}
+		stream, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(&s.peer))...)
+		if err != nil {
+			s.done(err)
+			return nil, err
+		}
+		s.ClientStream = stream
+		return s, nil
+	}
+}
+
+// loggedClientStream writes the access log of a client stream once when it ends.
+type loggedClientStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	logger        *accesslog.Logger
+	method        string
+	startTime     time.Time
+	serverStreams bool
+	peer          peer.Peer
+	once          sync.Once
+}
+
+func (s *loggedClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF表示流已结束，状态由RecvMsg返回
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.done(err)
+	}
+	return err
+}
+
+func (s *loggedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.done(nil)
+	case err != nil:
+		s.done(err)
+	case !s.serverStreams:
+		s.done(nil)
+	}
+	return err
+}
+
+func (s *loggedClientStream) done(err error) {
+	s.once.Do(func() {
+		e := accesslog.Entry{
+			Kind:    logKind,
+			Method:  s.method,
+			Caller:  outgoingCaller(s.ctx),
+			Code:    status.Code(err),
+			Err:     err,
+			Latency: time.Since(s.startTime),
+		}
+		if s.peer.Addr != nil {
+			e.Peer = s.peer.Addr.String()
+		}
+		s.logger.Log(s.ctx, e)
+	})
+}
+
+func outgoingCaller(ctx context.Context) string {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(CallerKey); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -2,10 +2,13 @@ package clientinterceptors
 
 import (
 	"context"
+	"errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
+	"io"
 	"mymicro/micro/core/metric"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -28,8 +31,32 @@ var (
 		Help:      "rpc server requests code count.",
 		Labels:    []string{"method", "code"},
 	})
+
+	metricClientStreamMsgTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_stream_msg_total",
+		Help:      "rpc client stream messages count.",
+		Labels:    []string{"method", "direction"},
+	})
 )
 
+const (
+	directionSent     = "sent"
+	directionReceived = "received"
+)
+
+// monitoredClientStream counts the messages of a client stream,
+// and records the duration and code when the stream ends.
+type monitoredClientStream struct {
+	grpc.ClientStream
+	method    string
+	startTime time.Time
+	// serverStreams is false if the server sends a single response, the stream ends with it.
+	serverStreams bool
+	once          sync.Once
+}
+
 func PrometheusInterceptor() grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
 		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
@@ -42,3 +69,54 @@ func PrometheusInterceptor() grpc.UnaryClientInterceptor {
 		return err
 	}
 }
+
+// StreamPrometheusInterceptor returns a func that records the duration, code and message counts of streams.
+func StreamPrometheusInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		startTime := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			metricServerReqDur.Observe(int64(time.Since(startTime)/time.Millisecond), method)
+			metricServerReqCodeTotal.Inc(method, strconv.Itoa(int(status.Code(err))))
+			return nil, err
+		}
+		return &monitoredClientStream{ClientStream: stream, method: method, startTime: startTime,
+			serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+func (s *monitoredClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		metricClientStreamMsgTotal.Inc(s.method, directionSent)
+	} else if !errors.Is(err, io.EOF) {
+		s.done(err)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		metricClientStreamMsgTotal.Inc(s.method, directionReceived)
+		// 客户端流只有一个响应，收到后不会再读到io.EOF
+		if !s.serverStreams {
+			s.done(nil)
+		}
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		s.done(nil)
+	} else {
+		s.done(err)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) done(err error) {
+	s.once.Do(func() {
+		metricServerReqDur.Observe(int64(time.Since(s.startTime)/time.Millisecond), s.method)
+		metricServerReqCodeTotal.Inc(s.method, strconv.Itoa(int(status.Code(err))))
+	})
+}
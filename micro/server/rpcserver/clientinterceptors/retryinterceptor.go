@@ -0,0 +1,293 @@
+package clientinterceptors
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/clock"
+	"mymicro/micro/core/metric"
+)
+
+const (
+	// RetryAttemptsKey is the span attribute of the number of attempts.
+	RetryAttemptsKey = attribute.Key("rpc.retry.attempts")
+
+	retryEvent = "retry"
+)
+
+var (
+	metricRetryAttempts = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_attempts",
+		Help:      "rpc client requests attempts per call.",
+		Labels:    []string{"method"},
+		Buckets:   []float64{1, 2, 3, 4, 5},
+	})
+
+	metricRetryTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_retry_total",
+		Help:      "rpc client requests retry count.",
+		Labels:    []string{"method", "code"},
+	})
+
+	metricRetryThrottled = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_retry_throttled_total",
+		Help:      "rpc client requests retry throttled by budget count.",
+		Labels:    []string{"method"},
+	})
+)
+
+type (
+	// RetryConf defines the retry policy of gRPC methods.
+	RetryConf struct {
+		// MaxAttempts is the max number of attempts including the first one, <= 1 disables retry.
+		MaxAttempts int
+		// Codes are the retryable status codes, codes.Unavailable if empty.
+		Codes []codes.Code
+		// InitialBackoff is the backoff before the first retry.
+		InitialBackoff time.Duration
+		// MaxBackoff caps the backoff.
+		MaxBackoff time.Duration
+		// Multiplier grows the backoff after each retry.
+		Multiplier float64
+		// Jitter randomizes the backoff by ±Jitter, in [0, 1].
+		Jitter float64
+	}
+
+	// MethodRetryConf defines specified retry policy for gRPC method.
+	MethodRetryConf struct {
+		FullMethod string
+		RetryConf
+		// Disable opts the method out of retry, e.g. non-idempotent methods.
+		Disable bool
+	}
+
+	// RetryBudgetConf defines the token bucket which throttles retries, see gRPC A6 retry throttling.
+	// Each failed attempt takes one token, each success gives TokenRatio back,
+	// retries are allowed only when more than half of MaxTokens are left.
+	RetryBudgetConf struct {
+		MaxTokens  float64
+		TokenRatio float64
+	}
+
+	// RetryOption is retry interceptor option.
+	RetryOption func(o *retryOptions)
+
+	retryOptions struct {
+		methods map[string]MethodRetryConf
+		budget  RetryBudgetConf
+		clock   clock.Clock
+	}
+
+	retryBudget struct {
+		mu         sync.Mutex
+		tokens     float64
+		maxTokens  float64
+		tokenRatio float64
+	}
+)
+
+// DefaultRetryConf returns the default retry policy.
+func DefaultRetryConf() RetryConf {
+	return RetryConf{
+		MaxAttempts:    3,
+		Codes:          []codes.Code{codes.Unavailable},
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithMethodRetry sets the retry policy of specified methods.
+func WithMethodRetry(confs ...MethodRetryConf) RetryOption {
+	return func(o *retryOptions) {
+		for _, c := range confs {
+			if c.FullMethod != "" {
+				o.methods[c.FullMethod] = c
+			}
+		}
+	}
+}
+
+// WithRetryDisabled opts the methods out of retry, e.g. non-idempotent methods.
+func WithRetryDisabled(fullMethods ...string) RetryOption {
+	return func(o *retryOptions) {
+		for _, m := range fullMethods {
+			o.methods[m] = MethodRetryConf{FullMethod: m, Disable: true}
+		}
+	}
+}
+
+// WithRetryBudget sets the retry budget.
+func WithRetryBudget(budget RetryBudgetConf) RetryOption {
+	return func(o *retryOptions) {
+		o.budget = budget
+	}
+}
+
+// WithRetryClock sets the clock waiting for the backoff, e.g. a fake clock in tests.
+func WithRetryClock(c clock.Clock) RetryOption {
+	return func(o *retryOptions) {
+		o.clock = c
+	}
+}
+
+// RetryInterceptor returns a func that retries failed unary calls with exponential backoff.
+func RetryInterceptor(conf RetryConf, opts ...RetryOption) grpc.UnaryClientInterceptor {
+	o := retryOptions{
+		methods: make(map[string]MethodRetryConf),
+		budget:  RetryBudgetConf{MaxTokens: 10, TokenRatio: 0.1},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	budget := &retryBudget{
+		tokens:     o.budget.MaxTokens,
+		maxTokens:  o.budget.MaxTokens,
+		tokenRatio: o.budget.TokenRatio,
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c := conf
+		if mc, ok := o.methods[method]; ok {
+			if mc.Disable {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			c = mc.RetryConf
+		}
+		if c.MaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		span := trace.SpanFromContext(ctx)
+		var (
+			err     error
+			attempt int
+		)
+		for attempt = 1; ; attempt++ {
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			code := status.Code(err)
+			if err == nil {
+				budget.onSuccess()
+				break
+			}
+			if !c.retryable(code) {
+				break
+			}
+			budget.onFailure()
+			if attempt >= c.MaxAttempts {
+				break
+			}
+			if !budget.allow() {
+				metricRetryThrottled.Inc(method)
+				break
+			}
+			backoff := c.backoff(attempt)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= backoff {
+				break
+			}
+			if !o.sleep(ctx, backoff) {
+				break
+			}
+			metricRetryTotal.Inc(method, strconv.Itoa(int(code)))
+			span.AddEvent(retryEvent, trace.WithAttributes(RetryAttemptsKey.Int(attempt+1)))
+		}
+		span.SetAttributes(RetryAttemptsKey.Int(attempt))
+		metricRetryAttempts.Observe(int64(attempt), method)
+		return err
+	}
+}
+
+func (c RetryConf) retryable(code codes.Code) bool {
+	if len(c.Codes) == 0 {
+		return code == codes.Unavailable
+	}
+	for _, rc := range c.Codes {
+		if rc == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the backoff before the next attempt of the given attempt.
+func (c RetryConf) backoff(attempt int) time.Duration {
+	multiplier := c.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(c.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if c.MaxBackoff > 0 && backoff > float64(c.MaxBackoff) {
+		backoff = float64(c.MaxBackoff)
+	}
+	if c.Jitter > 0 {
+		backoff *= 1 + c.Jitter*(rand.Float64()*2-1)
+	}
+	return time.Duration(backoff)
+}
+
+// sleep waits for d, returns false if ctx is done before.
+func (o *retryOptions) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	if o.clock != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-o.clock.After(d):
+			return true
+		}
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (b *retryBudget) allow() bool {
+	if b.maxTokens <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.maxTokens/2
+}
+
+func (b *retryBudget) onFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens--
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+}
+
+func (b *retryBudget) onSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.tokenRatio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
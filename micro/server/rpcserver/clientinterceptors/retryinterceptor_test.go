@@ -0,0 +1,74 @@
+package clientinterceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func failingInvoker(calls *int, failures int, code codes.Code) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return status.Error(code, "fail")
+		}
+		return nil
+	}
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	conf := DefaultRetryConf()
+	conf.InitialBackoff = time.Millisecond
+
+	tests := []struct {
+		name     string
+		opts     []RetryOption
+		failures int
+		code     codes.Code
+		calls    int
+		wantErr  bool
+	}{
+		{name: "success after retry", failures: 2, code: codes.Unavailable, calls: 3},
+		{name: "max attempts", failures: 5, code: codes.Unavailable, calls: 3, wantErr: true},
+		{name: "not retryable", failures: 1, code: codes.InvalidArgument, calls: 1, wantErr: true},
+		{name: "disabled", opts: []RetryOption{WithRetryDisabled("/foo")},
+			failures: 1, code: codes.Unavailable, calls: 1, wantErr: true},
+		{name: "budget", opts: []RetryOption{WithRetryBudget(RetryBudgetConf{MaxTokens: 2, TokenRatio: 0.1})},
+			failures: 5, code: codes.Unavailable, calls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			interceptor := RetryInterceptor(conf, tt.opts...)
+			err := interceptor(context.Background(), "/foo", nil, nil, nil,
+				failingInvoker(&calls, tt.failures, tt.code))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expect error %v, got %v", tt.wantErr, err)
+			}
+			if calls != tt.calls {
+				t.Errorf("expect %d calls, got %d", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestRetryInterceptorDeadline(t *testing.T) {
+	conf := DefaultRetryConf()
+	conf.InitialBackoff = time.Millisecond * 100
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	var calls int
+	err := RetryInterceptor(conf)(ctx, "/foo", nil, nil, nil, failingInvoker(&calls, 5, codes.Unavailable))
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expect %v, got %v", codes.Unavailable, err)
+	}
+	if calls != 1 {
+		t.Errorf("expect 1 call, got %d", calls)
+	}
+}
@@ -0,0 +1,150 @@
+package clientinterceptors
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"mymicro/micro/core/metric"
+)
+
+// clientNamespace is the namespace of the client metrics, serverNamespace is a misnomer kept by the older ones.
+const clientNamespace = serverNamespace
+
+var (
+	// defaultSingleflightMetadataKeys keeps the calls of different users or callers from sharing responses.
+	defaultSingleflightMetadataKeys = []string{"authorization", CallerKey}
+
+	metricCoalescedTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_coalesced_total",
+		Help:      "rpc client requests coalesced into in-flight calls count.",
+		Labels:    []string{"method"},
+	})
+)
+
+// MethodSingleflightConf defines the request coalescing of an idempotent read gRPC method.
+type MethodSingleflightConf struct {
+	FullMethod string
+	// MaxWait is the max time to wait for the in-flight call of another caller, the call is sent by itself
+	// after it, 0 waits until the in-flight call returns or ctx is done.
+	MaxWait time.Duration
+	// MetadataKeys are the outgoing metadata keys that must be equal for the calls to be coalesced,
+	// "authorization" and CallerKey if nil.
+	MetadataKeys []string
+}
+
+// SingleflightInterceptor returns a func that coalesces the identical in-flight unary calls of the given methods
+// into a single call, the calls are identical if they have the same method, request and selected metadata.
+// Each caller gets a clone of the response. The call is sent with the ctx and call options of the first caller,
+// so grpc.Header/grpc.Trailer call options are not supported for the methods, and the others send their own calls
+// if it is canceled by the first caller.
+func SingleflightInterceptor(methods ...MethodSingleflightConf) grpc.UnaryClientInterceptor {
+	confs := make(map[string]MethodSingleflightConf, len(methods))
+	for _, m := range methods {
+		if m.FullMethod == "" {
+			continue
+		}
+		if m.MetadataKeys == nil {
+			m.MetadataKeys = defaultSingleflightMetadataKeys
+		}
+		confs[m.FullMethod] = m
+	}
+	var group singleflight.Group
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		conf, ok := confs[method]
+		in, isProtoReq := req.(proto.Message)
+		out, isProtoReply := reply.(proto.Message)
+		if !ok || !isProtoReq || !isProtoReply {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		key, err := singleflightKey(ctx, method, in, conf.MetadataKeys)
+		if err != nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		leader := make(chan struct{})
+		ch := group.DoChan(key, func() (interface{}, error) {
+			close(leader)
+			r := out.ProtoReflect().New().Interface()
+			return r, invoker(ctx, method, req, r, cc, opts...)
+		})
+		var timeout <-chan time.Time
+		if conf.MaxWait > 0 {
+			timer := time.NewTimer(conf.MaxWait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		for {
+			select {
+			case res := <-ch:
+				if !isLeader(leader) {
+					metricCoalescedTotal.Inc(method)
+					// 首个调用方取消或超时，其他调用方自己重新发送
+					if isContextError(res.Err) && ctx.Err() == nil {
+						return invoker(ctx, method, req, reply, cc, opts...)
+					}
+				}
+				if res.Err != nil {
+					return res.Err
+				}
+				proto.Reset(out)
+				proto.Merge(out, res.Val.(proto.Message))
+				return nil
+			case <-ctx.Done():
+				return status.FromContextError(ctx.Err()).Err()
+			case <-timeout:
+				// 只有等待其他调用方的调用受MaxWait限制
+				if isLeader(leader) {
+					timeout = nil
+					continue
+				}
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+		}
+	}
+}
+
+func singleflightKey(ctx context.Context, method string, req proto.Message, keys []string) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(method)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(strings.Join(md.Get(k), ","))
+	}
+	sb.WriteByte(0)
+	sb.Write(data)
+	return sb.String(), nil
+}
+
+func isLeader(leader chan struct{}) bool {
+	select {
+	case <-leader:
+		return true
+	default:
+		return false
+	}
+}
+
+func isContextError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	code := status.Code(err)
+	return code == codes.Canceled || code == codes.DeadlineExceeded
+}
@@ -0,0 +1,136 @@
+package clientinterceptors
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// blockingInvoker replies the request after release is closed.
+func blockingInvoker(calls *int32, release chan struct{}) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		atomic.AddInt32(calls, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		reply.(*wrapperspb.StringValue).Value = "reply " + req.(*wrapperspb.StringValue).Value
+		return nil
+	}
+}
+
+func TestSingleflightInterceptor(t *testing.T) {
+	interceptor := SingleflightInterceptor(MethodSingleflightConf{FullMethod: "/foo"})
+
+	tests := []struct {
+		name    string
+		method  string
+		callers []metadata.MD
+		calls   int32
+	}{
+		{name: "coalesced", method: "/foo", callers: []metadata.MD{nil, nil, nil, nil}, calls: 1},
+		{name: "not enabled", method: "/bar", callers: []metadata.MD{nil, nil, nil}, calls: 3},
+		{name: "metadata", method: "/foo", callers: []metadata.MD{
+			metadata.Pairs(CallerKey, "a"), metadata.Pairs(CallerKey, "a"), metadata.Pairs(CallerKey, "b"),
+		}, calls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			release := make(chan struct{})
+			invoker := blockingInvoker(&calls, release)
+			replies := make([]*wrapperspb.StringValue, len(tt.callers))
+			var wg sync.WaitGroup
+			for i, md := range tt.callers {
+				wg.Add(1)
+				replies[i] = &wrapperspb.StringValue{}
+				go func(md metadata.MD, reply *wrapperspb.StringValue) {
+					defer wg.Done()
+					ctx := metadata.NewOutgoingContext(context.Background(), md)
+					if err := interceptor(ctx, tt.method, wrapperspb.String("req"), reply, nil, invoker); err != nil {
+						t.Error(err)
+					}
+				}(md, replies[i])
+			}
+			// 等待所有调用进入等待后再返回
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if calls != tt.calls {
+				t.Errorf("expect %d calls, got %d", tt.calls, calls)
+			}
+			for _, r := range replies {
+				if r.Value != "reply req" {
+					t.Errorf("expect reply req, got %s", r.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestSingleflightInterceptorFallback(t *testing.T) {
+	interceptor := SingleflightInterceptor(MethodSingleflightConf{FullMethod: "/foo", MaxWait: 20 * time.Millisecond})
+	var calls int32
+	release := make(chan struct{})
+	invoker := blockingInvoker(&calls, release)
+
+	// 首个调用一直阻塞，等待超过MaxWait的调用方自己发送
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		leaderDone <- interceptor(leaderCtx, "/foo", wrapperspb.String("req"), &wrapperspb.StringValue{}, nil, invoker)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	reply := &wrapperspb.StringValue{}
+	go func() {
+		done <- interceptor(context.Background(), "/foo", wrapperspb.String("req"), reply, nil, invoker)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expect 2 calls after max wait, got %d", n)
+	}
+	close(release)
+	if err := <-done; err != nil || reply.Value != "reply req" {
+		t.Errorf("expect reply req, got %v %s", err, reply.Value)
+	}
+	if err := <-leaderDone; err != nil {
+		t.Errorf("expect leader reply, got %v", err)
+	}
+	cancel()
+
+	// 首个调用方取消时，其他调用方自己重新发送
+	calls = 0
+	release = make(chan struct{})
+	invoker = blockingInvoker(&calls, release)
+	interceptor = SingleflightInterceptor(MethodSingleflightConf{FullMethod: "/foo"})
+	leaderCtx, cancel = context.WithCancel(context.Background())
+	go func() {
+		leaderDone <- interceptor(leaderCtx, "/foo", wrapperspb.String("req"), &wrapperspb.StringValue{}, nil, invoker)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		done <- interceptor(context.Background(), "/foo", wrapperspb.String("req"), reply, nil, invoker)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-leaderDone
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("expect reply after leader canceled, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expect 2 calls, got %d", n)
+	}
+}
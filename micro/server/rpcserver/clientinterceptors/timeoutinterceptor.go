@@ -2,13 +2,50 @@ package clientinterceptors
 
 import (
 	"context"
-	"google.golang.org/grpc"
 	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func TimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+type (
+	// MethodTimeoutConf defines specified timeout for gRPC method.
+	MethodTimeoutConf struct {
+		FullMethod string        `json:"full-method" mapstructure:"full-method"`
+		Timeout    time.Duration `json:"timeout" mapstructure:"timeout"`
+	}
+
+	// TimeoutConf defines the timeouts of the client calls.
+	// The deadline of a call is the shortest of its timeout and the deadline budget left in ctx,
+	// which is propagated to the downstream services by gRPC, so each hop takes SafetyMargin off the budget.
+	TimeoutConf struct {
+		// Timeout is the default timeout of the calls, 0 means no timeout.
+		Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+		// MaxTimeout caps the deadline of the calls, including the deadline inherited from ctx, 0 means no cap.
+		MaxTimeout time.Duration `json:"max-timeout" mapstructure:"max-timeout"`
+		// SafetyMargin is kept from the deadline budget for the caller to handle the response.
+		SafetyMargin time.Duration `json:"safety-margin" mapstructure:"safety-margin"`
+		// MinBudget fails the calls fast with DeadlineExceeded if the budget left is less than it.
+		MinBudget time.Duration `json:"min-budget" mapstructure:"min-budget"`
+		// Methods overrides Timeout for the methods.
+		Methods []MethodTimeoutConf `json:"methods" mapstructure:"methods"`
+	}
+)
+
+// TimeoutInterceptor returns a func that sets the deadline of the calls by conf.
+func TimeoutInterceptor(conf TimeoutConf) grpc.UnaryClientInterceptor {
+	timeouts := methodTimeouts(conf)
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
 		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeout, ok := timeouts[method]
+		if !ok {
+			timeout = conf.Timeout
+		}
+		timeout, err := conf.timeout(ctx, method, timeout)
+		if err != nil {
+			return err
+		}
 		if timeout <= 0 {
 			return invoker(ctx, method, req, reply, cc, opts...)
 		}
@@ -17,3 +54,70 @@ func TimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
 		return invoker(ctx, method, req, reply, cc, opts...)
 	}
 }
+
+// StreamTimeoutInterceptor returns a func that sets the deadline of the streams by conf.
+// The default Timeout is not applied to streams that live longer than unary calls,
+// only the method timeouts, MaxTimeout and the deadline budget left in ctx are.
+func StreamTimeoutInterceptor(conf TimeoutConf) grpc.StreamClientInterceptor {
+	timeouts := methodTimeouts(conf)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		timeout, err := conf.timeout(ctx, method, timeouts[method])
+		if err != nil {
+			return nil, err
+		}
+		if timeout <= 0 {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &timeoutClientStream{ClientStream: stream, cancel: cancel}, nil
+	}
+}
+
+// timeoutClientStream releases the timer of the deadline when the stream ends.
+type timeoutClientStream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *timeoutClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+func methodTimeouts(conf TimeoutConf) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(conf.Methods))
+	for _, m := range conf.Methods {
+		if m.FullMethod != "" {
+			timeouts[m.FullMethod] = m.Timeout
+		}
+	}
+	return timeouts
+}
+
+// timeout returns the timeout of the call capped by MaxTimeout and the deadline budget left in ctx,
+// 0 means no timeout.
+func (conf TimeoutConf) timeout(ctx context.Context, method string, timeout time.Duration) (time.Duration, error) {
+	if conf.MaxTimeout > 0 && (timeout <= 0 || timeout > conf.MaxTimeout) {
+		timeout = conf.MaxTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		budget := time.Until(deadline) - conf.SafetyMargin
+		// 剩余预算不足时直接失败，避免下游做无用功
+		if budget <= 0 || budget < conf.MinBudget {
+			return 0, status.Errorf(codes.DeadlineExceeded, "deadline budget %v of %s is exhausted", budget, method)
+		}
+		if timeout <= 0 || budget < timeout {
+			timeout = budget
+		}
+	}
+	return timeout, nil
+}
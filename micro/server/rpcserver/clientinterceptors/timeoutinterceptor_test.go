@@ -0,0 +1,128 @@
+package clientinterceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTimeoutInterceptor(t *testing.T) {
+	conf := TimeoutConf{
+		Timeout:      time.Second,
+		MaxTimeout:   3 * time.Second,
+		SafetyMargin: 100 * time.Millisecond,
+		MinBudget:    50 * time.Millisecond,
+		Methods: []MethodTimeoutConf{
+			{FullMethod: "/slow", Timeout: 2 * time.Second},
+			{FullMethod: "/long", Timeout: time.Minute},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		deadline time.Duration
+		timeout  time.Duration
+		calls    int
+	}{
+		{name: "default", method: "/foo", timeout: time.Second, calls: 1},
+		{name: "method", method: "/slow", timeout: 2 * time.Second, calls: 1},
+		{name: "max timeout", method: "/long", timeout: 3 * time.Second, calls: 1},
+		{name: "max inherited", method: "/long", deadline: time.Minute, timeout: 3 * time.Second, calls: 1},
+		{name: "budget", method: "/foo", deadline: 500 * time.Millisecond, timeout: 400 * time.Millisecond, calls: 1},
+		{name: "exhausted", method: "/foo", deadline: 120 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			var calls int
+			var timeout time.Duration
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+				opts ...grpc.CallOption) error {
+				calls++
+				deadline, _ := ctx.Deadline()
+				timeout = time.Until(deadline)
+				return nil
+			}
+			err := TimeoutInterceptor(conf)(ctx, tt.method, nil, nil, nil, invoker)
+			if calls != tt.calls {
+				t.Fatalf("expect %d calls, got %d", tt.calls, calls)
+			}
+			if tt.calls == 0 {
+				if status.Code(err) != codes.DeadlineExceeded {
+					t.Errorf("expect %v, got %v", codes.DeadlineExceeded, err)
+				}
+				return
+			}
+			if timeout > tt.timeout || timeout < tt.timeout-20*time.Millisecond {
+				t.Errorf("expect timeout %v, got %v", tt.timeout, timeout)
+			}
+		})
+	}
+}
+
+func TestStreamTimeoutInterceptor(t *testing.T) {
+	conf := TimeoutConf{
+		Timeout:      time.Second,
+		SafetyMargin: 100 * time.Millisecond,
+		MinBudget:    50 * time.Millisecond,
+		Methods:      []MethodTimeoutConf{{FullMethod: "/watch", Timeout: 2 * time.Second}},
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		deadline time.Duration
+		timeout  time.Duration
+		calls    int
+	}{
+		{name: "no default timeout", method: "/foo", calls: 1},
+		{name: "method", method: "/watch", timeout: 2 * time.Second, calls: 1},
+		{name: "budget", method: "/foo", deadline: 500 * time.Millisecond, timeout: 400 * time.Millisecond, calls: 1},
+		{name: "exhausted", method: "/foo", deadline: 120 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			var calls int
+			var timeout time.Duration
+			streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+				opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				calls++
+				if deadline, ok := ctx.Deadline(); ok {
+					timeout = time.Until(deadline)
+				}
+				return nil, nil
+			}
+			_, err := StreamTimeoutInterceptor(conf)(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, tt.method, streamer)
+			if calls != tt.calls {
+				t.Fatalf("expect %d calls, got %d", tt.calls, calls)
+			}
+			if tt.calls == 0 {
+				if status.Code(err) != codes.DeadlineExceeded {
+					t.Errorf("expect %v, got %v", codes.DeadlineExceeded, err)
+				}
+				return
+			}
+			if timeout > tt.timeout || timeout < tt.timeout-20*time.Millisecond {
+				t.Errorf("expect timeout %v, got %v", tt.timeout, timeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"mymicro/micro/registry"
+	"mymicro/micro/server/rpcserver/clientinterceptors"
+	"mymicro/pkg/log"
+)
+
+type (
+	// ClientConf is the config of the client connection of a target service.
+	ClientConf struct {
+		// Target is the service name resolved by discovery, or an endpoint such as direct:///127.0.0.1:9000.
+		Target string `json:"target" mapstructure:"target"`
+		// Secure dials with TLS, the certificates are set by the client options of the target.
+		Secure      bool `json:"secure" mapstructure:"secure"`
+		Metrics     bool `json:"metrics" mapstructure:"metrics"`
+		HealthCheck bool `json:"health-check" mapstructure:"health-check"`
+		Breaker     bool `json:"breaker" mapstructure:"breaker"`
+		// Timeout is the timeouts and the deadline budget of the calls, the default client timeout is used
+		// if Timeout.Timeout is 0.
+		Timeout clientinterceptors.TimeoutConf `json:"timeout" mapstructure:"timeout"`
+	}
+
+	// ConnState is the state of a managed connection.
+	ConnState struct {
+		Conf  ClientConf
+		State connectivity.State
+		// Active is the number of calls in flight.
+		Active int64
+		// Idle is how long the connection has been idle, 0 if it is active.
+		Idle time.Duration
+	}
+
+	// ClientManagerOption is client manager option.
+	ClientManagerOption func(m *ClientManager)
+
+	// ClientManager shares the client connections keyed by the target service and its config,
+	// so that the callers of the same service share one connection and one discovery watch.
+	// The connections idle longer than the TTL are closed, callers should get the connection
+	// from the manager for each use instead of keeping it.
+	ClientManager struct {
+		discovery  registry.Discovery
+		idleTTL    time.Duration
+		confs      map[string]ClientConf
+		defaults   []ClientOption
+		targetOpts map[string][]ClientOption
+
+		dials  singleflight.Group
+		mu     sync.Mutex
+		conns  map[string]*managedConn
+		closed bool
+		done   chan struct{}
+	}
+
+	managedConn struct {
+		*grpc.ClientConn
+		conf       ClientConf
+		active     int64
+		lastActive int64
+	}
+)
+
+// WithManagerDiscovery sets the discovery resolving the target services.
+func WithManagerDiscovery(d registry.Discovery) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.discovery = d
+	}
+}
+
+// WithIdleTTL sets how long an idle connection is kept, 10 minutes by default, 0 keeps them until Close.
+func WithIdleTTL(ttl time.Duration) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.idleTTL = ttl
+	}
+}
+
+// WithClientConfs sets the config of each target, e.g. loaded from the config file.
+func WithClientConfs(confs ...ClientConf) ClientManagerOption {
+	return func(m *ClientManager) {
+		for _, c := range confs {
+			m.confs[c.Target] = c
+		}
+	}
+}
+
+// WithDefaultClientOptions sets the client options of all the targets.
+func WithDefaultClientOptions(opts ...ClientOption) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.defaults = append(m.defaults, opts...)
+	}
+}
+
+// WithTargetClientOptions sets the client options of the target, e.g. interceptors and certificates,
+// they are applied after the default ones.
+func WithTargetClientOptions(target string, opts ...ClientOption) ClientManagerOption {
+	return func(m *ClientManager) {
+		m.targetOpts[target] = append(m.targetOpts[target], opts...)
+	}
+}
+
+// NewClientManager creates a client manager.
+func NewClientManager(opts ...ClientManagerOption) *ClientManager {
+	m := &ClientManager{
+		idleTTL:    10 * time.Minute,
+		confs:      make(map[string]ClientConf),
+		targetOpts: make(map[string][]ClientOption),
+		conns:      make(map[string]*managedConn),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.idleTTL > 0 {
+		go m.closeIdleLoop()
+	}
+	return m
+}
+
+// Get returns the shared connection of the target by its config set by WithClientConfs,
+// the target is dialed insecurely with the default config if it is not configured.
+func (m *ClientManager) Get(ctx context.Context, target string) (*grpc.ClientConn, error) {
+	conf, ok := m.confs[target]
+	if !ok {
+		conf = ClientConf{Target: target}
+	}
+	return m.GetConf(ctx, conf)
+}
+
+// GetConf returns the shared connection of the config.
+func (m *ClientManager) GetConf(ctx context.Context, conf ClientConf) (*grpc.ClientConn, error) {
+	key := conf.key()
+	if conn, ok, err := m.getConn(key); ok {
+		return conn, err
+	}
+
+	// 拨号不持有锁，相同配置的并发拨号只拨一次
+	v, err, _ := m.dials.Do(key, func() (interface{}, error) {
+		if conn, ok, err := m.getConn(key); ok {
+			return conn, err
+		}
+		mc := &managedConn{conf: conf, lastActive: time.Now().UnixNano()}
+		opts := m.clientOptions(conf, mc)
+		var (
+			conn *grpc.ClientConn
+			err  error
+		)
+		if conf.Secure {
+			conn, err = Dail(ctx, opts...)
+		} else {
+			conn, err = DailInsecure(ctx, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mc.ClientConn = conn
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.closed {
+			_ = conn.Close()
+			return nil, grpc.ErrClientConnClosing
+		}
+		if exist, ok := m.conns[key]; ok {
+			_ = conn.Close()
+			return exist.ClientConn, nil
+		}
+		m.conns[key] = mc
+		log.Infof("[gRPC] client of %s created", conf.Target)
+		return conn, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*grpc.ClientConn), nil
+}
+
+// getConn returns the connection of the key if it exists or the manager is closed.
+func (m *ClientManager) getConn(key string) (*grpc.ClientConn, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, true, grpc.ErrClientConnClosing
+	}
+	if mc, ok := m.conns[key]; ok {
+		atomic.StoreInt64(&mc.lastActive, time.Now().UnixNano())
+		return mc.ClientConn, true, nil
+	}
+	return nil, false, nil
+}
+
+func (m *ClientManager) clientOptions(conf ClientConf, mc *managedConn) []ClientOption {
+	endpoint := conf.Target
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "discovery:///" + endpoint
+	}
+	opts := []ClientOption{
+		WithEndpoint(endpoint),
+		WithEnableMetrics(conf.Metrics),
+		WithHealthCheck(conf.HealthCheck),
+	}
+	if m.discovery != nil {
+		opts = append(opts, WithDiscovery(m.discovery))
+	}
+	if conf.Timeout.Timeout > 0 {
+		opts = append(opts, WithTimeoutConf(conf.Timeout))
+	} else {
+		opts = append(opts, withTimeoutBudget(conf.Timeout))
+	}
+	if conf.Breaker {
+		opts = append(opts, WithBreaker())
+	}
+	opts = append(opts, m.defaults...)
+	opts = append(opts, m.targetOpts[conf.Target]...)
+	// 统计进行中的调用，空闲超过TTL的连接才会被关闭
+	return append(opts, WithClientOptions(
+		grpc.WithChainUnaryInterceptor(mc.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(mc.streamInterceptor),
+	))
+}
+
+// States returns the states of the managed connections.
+func (m *ClientManager) States() []ConnState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	states := make([]ConnState, 0, len(m.conns))
+	for _, mc := range m.conns {
+		states = append(states, ConnState{
+			Conf:   mc.conf,
+			State:  mc.GetState(),
+			Active: atomic.LoadInt64(&mc.active),
+			Idle:   mc.idle(now),
+		})
+	}
+	return states
+}
+
+// Close closes all the connections, Get fails after Close.
+func (m *ClientManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	for key, mc := range m.conns {
+		_ = mc.Close()
+		delete(m.conns, key)
+	}
+	log.Info("[gRPC] client manager closed")
+	return nil
+}
+
+// key identifies the connection of the conf, the conf is not comparable for its method timeouts.
+func (c ClientConf) key() string {
+	return fmt.Sprintf("%+v", c)
+}
+
+func (m *ClientManager) closeIdleLoop() {
+	ticker := time.NewTicker(m.idleTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.closeIdle(now)
+		}
+	}
+}
+
+func (m *ClientManager) closeIdle(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, mc := range m.conns {
+		if mc.idle(now) > m.idleTTL {
+			_ = mc.Close()
+			delete(m.conns, key)
+			log.Infof("[gRPC] idle client of %s closed", mc.conf.Target)
+		}
+	}
+}
+
+// idle returns how long the connection has been idle, 0 if it is active.
+func (mc *managedConn) idle(now time.Time) time.Duration {
+	if atomic.LoadInt64(&mc.active) > 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&mc.lastActive)))
+}
+
+func (mc *managedConn) begin() {
+	atomic.AddInt64(&mc.active, 1)
+}
+
+func (mc *managedConn) end() {
+	atomic.StoreInt64(&mc.lastActive, time.Now().UnixNano())
+	atomic.AddInt64(&mc.active, -1)
+}
+
+func (mc *managedConn) unaryInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	mc.begin()
+	defer mc.end()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (mc *managedConn) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	mc.begin()
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		mc.end()
+		return nil, err
+	}
+	// 流结束时才算调用结束
+	go func() {
+		<-cs.Context().Done()
+		mc.end()
+	}()
+	return cs, nil
+}
@@ -0,0 +1,107 @@
+package rpcserver
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"mymicro/micro/server/rpcserver/clientinterceptors"
+)
+
+func TestClientManager(t *testing.T) {
+	srv := NewServer(WithAddress("127.0.0.1:0"))
+	if srv == nil {
+		t.Fatal("expect server, got nil")
+	}
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	defer srv.Stop(context.Background())
+
+	target := "passthrough:///" + srv.lis.Addr().String()
+	m := NewClientManager(
+		WithIdleTTL(time.Hour),
+		WithClientConfs(ClientConf{Target: target, Timeout: clientinterceptors.TimeoutConf{Timeout: time.Second}}),
+	)
+	defer m.Close()
+
+	ctx := context.Background()
+	conn, err := m.Get(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	again, err := m.Get(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != conn {
+		t.Errorf("expect the connection reused")
+	}
+	// 不同的配置使用不同的连接
+	other, err := m.GetConf(ctx, ClientConf{Target: target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == conn {
+		t.Errorf("expect a new connection of another conf")
+	}
+
+	// 并发获取同一配置只拨号一次
+	concurrent := ClientConf{Target: target, Metrics: true}
+	var wg sync.WaitGroup
+	conns := make([]*grpc.ClientConn, 8)
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], _ = m.GetConf(ctx, concurrent)
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range conns {
+		if c == nil || c != conns[0] {
+			t.Fatalf("expect the connection shared by concurrent callers")
+		}
+	}
+
+	states := m.States()
+	if len(states) != 3 {
+		t.Fatalf("expect 3 states, got %d", len(states))
+	}
+	for _, s := range states {
+		if s.Active != 0 {
+			t.Errorf("expect 0 active calls, got %d", s.Active)
+		}
+	}
+
+	// 只有空闲超过TTL的连接被关闭
+	m.closeIdle(time.Now().Add(30 * time.Minute))
+	if n := len(m.States()); n != 3 {
+		t.Errorf("expect 3 connections, got %d", n)
+	}
+	m.closeIdle(time.Now().Add(2 * time.Hour))
+	if n := len(m.States()); n != 0 {
+		t.Errorf("expect idle connections closed, got %d", n)
+	}
+	if _, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err == nil {
+		t.Errorf("expect the closed connection failing")
+	}
+
+	if _, err = m.Get(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+	_ = m.Close()
+	if n := len(m.States()); n != 0 {
+		t.Errorf("expect connections closed, got %d", n)
+	}
+	if _, err = m.Get(ctx, target); err == nil {
+		t.Errorf("expect error after close")
+	}
+}
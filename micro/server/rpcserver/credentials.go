@@ -0,0 +1,56 @@
+package rpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+
+	srvintc "mymicro/micro/server/rpcserver/serverinterceptors"
+)
+
+// TokenSource returns the token of the outgoing call.
+type TokenSource func(ctx context.Context) (string, error)
+
+// tokenCredentials attaches the bearer token to each call.
+type tokenCredentials struct {
+	source  TokenSource
+	forward bool
+}
+
+var _ credentials.PerRPCCredentials = (*tokenCredentials)(nil)
+
+// StaticToken returns a TokenSource of the token.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// NewTokenCredentials returns the PerRPCCredentials attaching the bearer token of source,
+// the token of the inbound request is forwarded instead if forwardInbound and present.
+// source may be nil if only the inbound token is forwarded.
+func NewTokenCredentials(source TokenSource, forwardInbound bool) credentials.PerRPCCredentials {
+	return &tokenCredentials{source: source, forward: forwardInbound}
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	var token string
+	if c.forward {
+		token = srvintc.TokenFromContext(ctx)
+	}
+	if token == "" && c.source != nil {
+		var err error
+		if token, err = c.source(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return map[string]string{srvintc.AuthorizationKey: "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity returns false, the token is also sent on insecure connections inside the cluster.
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
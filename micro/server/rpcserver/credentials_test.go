@@ -0,0 +1,34 @@
+package rpcserver
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTokenCredentials(t *testing.T) {
+	inbound := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("authorization", "Bearer inbound"))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		forward bool
+		want    string
+	}{
+		{name: "static", ctx: context.Background(), want: "Bearer static"},
+		{name: "forward", ctx: inbound, forward: true, want: "Bearer inbound"},
+		{name: "not forward", ctx: inbound, want: "Bearer static"},
+		{name: "forward fallback", ctx: context.Background(), forward: true, want: "Bearer static"},
+	}
+	for _, tt := range tests {
+		md, err := NewTokenCredentials(StaticToken("static"), tt.forward).GetRequestMetadata(tt.ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if md["authorization"] != tt.want {
+			t.Errorf("%s: expect %s, got %s", tt.name, tt.want, md["authorization"])
+		}
+	}
+}
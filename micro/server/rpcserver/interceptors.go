@@ -0,0 +1,313 @@
+package rpcserver
+
+import (
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"mymicro/micro/server/restserver/validation"
+	srvintc "mymicro/micro/server/rpcserver/serverinterceptors"
+	"mymicro/pkg/log"
+)
+
+// Names of the default server interceptors.
+const (
+	InterceptorRecover   = "recover"
+	InterceptorTracing   = "tracing"
+	InterceptorLogging   = "logging"
+	InterceptorMetrics   = "metrics"
+	InterceptorShedding  = "shedding"
+	InterceptorAuth      = "auth"
+	InterceptorRateLimit = "ratelimit"
+	InterceptorValidate  = "validate"
+	InterceptorTimeout   = "timeout"
+	InterceptorFault     = "fault"
+	InterceptorErrors    = "errors"
+
+	// userInterceptor is the name of user interceptors in the chain.
+	userInterceptor = "user"
+)
+
+// defaultInterceptors is the order of the default server interceptors in the chain,
+// recover is inside tracing, logging and metrics so that the recovered panics are observed by them.
+var defaultInterceptors = []string{
+	InterceptorTracing,
+	InterceptorLogging,
+	InterceptorMetrics,
+	InterceptorRecover,
+	InterceptorShedding,
+	InterceptorAuth,
+	InterceptorRateLimit,
+	InterceptorValidate,
+	InterceptorTimeout,
+	InterceptorFault,
+	InterceptorErrors,
+}
+
+type (
+	// unaryInsertion inserts user interceptors before or after a default interceptor.
+	unaryInsertion struct {
+		anchor       string
+		after        bool
+		interceptors []grpc.UnaryServerInterceptor
+	}
+
+	// streamInsertion inserts user stream interceptors before or after a default interceptor.
+	streamInsertion struct {
+		anchor       string
+		after        bool
+		interceptors []grpc.StreamServerInterceptor
+	}
+)
+
+// WithInterceptorEnabled enables or disables the default interceptor of name.
+// recover, tracing, validate and errors are enabled by default, metrics by WithMetrics,
+// and the others are enabled once configured by WithAccessLog, WithShedder, WithAuth, WithRateLimiter, WithTimeout
+// and WithFaultInjector.
+func WithInterceptorEnabled(name string, enabled bool) ServerOption {
+	return func(s *Server) {
+		if s.interceptorSwitches == nil {
+			s.interceptorSwitches = make(map[string]bool)
+		}
+		s.interceptorSwitches[name] = enabled
+	}
+}
+
+// WithUnaryInterceptorBefore inserts the unary interceptors before the default interceptor of name,
+// the position is kept even if the default interceptor is disabled.
+func WithUnaryInterceptorBefore(name string, ins ...grpc.UnaryServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.unaryInsertions = append(s.unaryInsertions, unaryInsertion{anchor: name, interceptors: ins})
+	}
+}
+
+// WithUnaryInterceptorAfter inserts the unary interceptors after the default interceptor of name,
+// the position is kept even if the default interceptor is disabled.
+func WithUnaryInterceptorAfter(name string, ins ...grpc.UnaryServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.unaryInsertions = append(s.unaryInsertions, unaryInsertion{anchor: name, after: true, interceptors: ins})
+	}
+}
+
+// WithStreamInterceptorBefore inserts the stream interceptors before the default interceptor of name,
+// the position is kept even if the default interceptor is disabled.
+func WithStreamInterceptorBefore(name string, ins ...grpc.StreamServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.streamInsertions = append(s.streamInsertions, streamInsertion{anchor: name, interceptors: ins})
+	}
+}
+
+// WithStreamInterceptorAfter inserts the stream interceptors after the default interceptor of name,
+// the position is kept even if the default interceptor is disabled.
+func WithStreamInterceptorAfter(name string, ins ...grpc.StreamServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.streamInsertions = append(s.streamInsertions, streamInsertion{anchor: name, after: true, interceptors: ins})
+	}
+}
+
+func (s *Server) interceptorEnabled(name string, byDefault bool) bool {
+	if enabled, ok := s.interceptorSwitches[name]; ok {
+		return enabled
+	}
+	return byDefault
+}
+
+// translator returns the translator of the validation messages, nil if it fails.
+func (s *Server) translator() ut.Translator {
+	if s.trans == nil {
+		trans, err := validation.NewTranslator(s.transName)
+		if err != nil {
+			log.Errorf("[gRPC] init translator %s error: %s", s.transName, err.Error())
+			return nil
+		}
+		s.trans = trans
+	}
+	return s.trans
+}
+
+func (s *Server) defaultUnaryInterceptor(name string) grpc.UnaryServerInterceptor {
+	switch name {
+	case InterceptorRecover:
+		if s.interceptorEnabled(name, true) {
+			return srvintc.UnaryRecoverInterceptor
+		}
+	case InterceptorTracing:
+		if s.interceptorEnabled(name, true) {
+			return otelgrpc.UnaryServerInterceptor()
+		}
+	case InterceptorLogging:
+		if s.interceptorEnabled(name, true) && s.accessLog != nil {
+			return srvintc.UnaryLoggingInterceptor(*s.accessLog)
+		}
+	case InterceptorMetrics:
+		if s.interceptorEnabled(name, s.enableMetrics) {
+			return srvintc.UnaryPrometheusInterceptor
+		}
+	case InterceptorShedding:
+		if s.interceptorEnabled(name, true) && s.shedder != nil {
+			return srvintc.UnarySheddingInterceptor(s.shedder)
+		}
+	case InterceptorAuth:
+		if s.interceptorEnabled(name, true) && s.tokenVerifier != nil {
+			return srvintc.UnaryAuthInterceptor(s.tokenVerifier, s.publicMethods...)
+		}
+	case InterceptorRateLimit:
+		if s.interceptorEnabled(name, true) && s.rateLimiter != nil {
+			return srvintc.UnaryRateLimitInterceptor(s.rateLimiter, s.caller)
+		}
+	case InterceptorValidate:
+		if s.interceptorEnabled(name, true) {
+			return srvintc.UnaryValidateInterceptor(s.translator())
+		}
+	case InterceptorTimeout:
+		if s.interceptorEnabled(name, true) && s.timeout > 0 {
+			return srvintc.UnaryTimeoutInterceptor(s.timeout)
+		}
+	case InterceptorFault:
+		if s.interceptorEnabled(name, true) && s.faultInjector.Enabled() {
+			return srvintc.UnaryFaultInterceptor(s.faultInjector, s.caller)
+		}
+	case InterceptorErrors:
+		if s.interceptorEnabled(name, true) {
+			return srvintc.UnaryErrorInterceptor
+		}
+	}
+	return nil
+}
+
+func (s *Server) defaultStreamInterceptor(name string) grpc.StreamServerInterceptor {
+	switch name {
+	case InterceptorRecover:
+		if s.interceptorEnabled(name, true) {
+			return srvintc.StreamRecoverInterceptor
+		}
+	case InterceptorTracing:
+		if s.interceptorEnabled(name, true) {
+			return otelgrpc.StreamServerInterceptor()
+		}
+	case InterceptorLogging:
+		if s.interceptorEnabled(name, true) && s.accessLog != nil {
+			return srvintc.StreamLoggingInterceptor(*s.accessLog)
+		}
+	case InterceptorMetrics:
+		if s.interceptorEnabled(name, s.enableMetrics) {
+			return srvintc.StreamPrometheusInterceptor
+		}
+	case InterceptorShedding:
+		if s.interceptorEnabled(name, true) && s.shedder != nil {
+			return srvintc.StreamSheddingInterceptor(s.shedder)
+		}
+	case InterceptorAuth:
+		if s.interceptorEnabled(name, true) && s.tokenVerifier != nil {
+			return srvintc.StreamAuthInterceptor(s.tokenVerifier, s.publicMethods...)
+		}
+	case InterceptorRateLimit:
+		if s.interceptorEnabled(name, true) && s.rateLimiter != nil {
+			return srvintc.StreamRateLimitInterceptor(s.rateLimiter, s.caller)
+		}
+	case InterceptorValidate:
+		if s.interceptorEnabled(name, true) {
+			return srvintc.StreamValidateInterceptor(s.translator())
+		}
+	case InterceptorTimeout:
+		if s.interceptorEnabled(name, true) && s.timeout > 0 {
+			return srvintc.StreamTimeoutInterceptor(s.timeout)
+		}
+	case InterceptorFault:
+		if s.interceptorEnabled(name, true) && s.faultInjector.Enabled() {
+			return srvintc.StreamFaultInterceptor(s.faultInjector, s.caller)
+		}
+	case InterceptorErrors:
+		if s.interceptorEnabled(name, true) {
+			return srvintc.StreamErrorInterceptor
+		}
+	}
+	return nil
+}
+
+// buildUnaryChain returns the unary interceptors and their names in order.
+func (s *Server) buildUnaryChain() ([]grpc.UnaryServerInterceptor, []string) {
+	var (
+		ints  []grpc.UnaryServerInterceptor
+		names []string
+	)
+	add := func(name string, ins ...grpc.UnaryServerInterceptor) {
+		for _, in := range ins {
+			ints = append(ints, in)
+			names = append(names, name)
+		}
+	}
+	insert := func(anchor string, after bool) {
+		for _, ins := range s.unaryInsertions {
+			if ins.anchor == anchor && ins.after == after {
+				add(userInterceptor, ins.interceptors...)
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(defaultInterceptors))
+	for _, name := range defaultInterceptors {
+		known[name] = true
+		insert(name, false)
+		if in := s.defaultUnaryInterceptor(name); in != nil {
+			add(name, in)
+		}
+		insert(name, true)
+	}
+	for _, ins := range s.unaryInsertions {
+		if !known[ins.anchor] {
+			log.Warnf("[gRPC] unknown interceptor %s, append the inserted interceptors to the chain", ins.anchor)
+			add(userInterceptor, ins.interceptors...)
+		}
+	}
+	add(userInterceptor, s.unaryInterceptors...)
+	return ints, names
+}
+
+// buildStreamChain returns the stream interceptors and their names in order.
+func (s *Server) buildStreamChain() ([]grpc.StreamServerInterceptor, []string) {
+	var (
+		ints  []grpc.StreamServerInterceptor
+		names []string
+	)
+	add := func(name string, ins ...grpc.StreamServerInterceptor) {
+		for _, in := range ins {
+			ints = append(ints, in)
+			names = append(names, name)
+		}
+	}
+	insert := func(anchor string, after bool) {
+		for _, ins := range s.streamInsertions {
+			if ins.anchor == anchor && ins.after == after {
+				add(userInterceptor, ins.interceptors...)
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(defaultInterceptors))
+	for _, name := range defaultInterceptors {
+		known[name] = true
+		insert(name, false)
+		if in := s.defaultStreamInterceptor(name); in != nil {
+			add(name, in)
+		}
+		insert(name, true)
+	}
+	for _, ins := range s.streamInsertions {
+		if !known[ins.anchor] {
+			log.Warnf("[gRPC] unknown interceptor %s, append the inserted stream interceptors to the chain", ins.anchor)
+			add(userInterceptor, ins.interceptors...)
+		}
+	}
+	add(userInterceptor, s.streamInterceptors...)
+	return ints, names
+}
+
+func chainString(names []string) string {
+	if len(names) == 0 {
+		return "<empty>"
+	}
+	return strings.Join(names, " -> ")
+}
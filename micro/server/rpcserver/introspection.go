@@ -0,0 +1,214 @@
+package rpcserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/balancer"
+
+	"mymicro/micro/core/metric"
+	"mymicro/micro/server/rpcserver/selector"
+)
+
+const (
+	selectorNamespace = "rpc_client"
+	// selectorMetricsInterval is the interval of refreshing selector gauges
+	selectorMetricsInterval = 5 * time.Second
+)
+
+var (
+	selectorNodeLabels = []string{"conn", "target", "service", "address"}
+
+	metricNodeWeight = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: selectorNamespace,
+		Subsystem: "selector",
+		Name:      "xhy_node_weight",
+		Help:      "rpc client selector node runtime weight.",
+		Labels:    selectorNodeLabels,
+	})
+
+	metricNodeLag = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: selectorNamespace,
+		Subsystem: "selector",
+		Name:      "xhy_node_lag_ms",
+		Help:      "rpc client selector node ewma lag(ms).",
+		Labels:    selectorNodeLabels,
+	})
+
+	metricNodeSuccess = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: selectorNamespace,
+		Subsystem: "selector",
+		Name:      "xhy_node_success_rate",
+		Help:      "rpc client selector node success rate.",
+		Labels:    selectorNodeLabels,
+	})
+
+	metricNodeInflight = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: selectorNamespace,
+		Subsystem: "selector",
+		Name:      "xhy_node_inflight",
+		Help:      "rpc client selector node inflight requests.",
+		Labels:    selectorNodeLabels,
+	})
+
+	metricNodeLastPick = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: selectorNamespace,
+		Subsystem: "selector",
+		Name:      "xhy_node_last_pick_seconds",
+		Help:      "rpc client selector node last pick unix time(s).",
+		Labels:    selectorNodeLabels,
+	})
+)
+
+// SelectorSnapshot is the runtime state of the selector of a client connection.
+type SelectorSnapshot struct {
+	ID       int64                   `json:"id"`
+	Target   string                  `json:"target"`
+	Balancer string                  `json:"balancer"`
+	Nodes    []selector.NodeSnapshot `json:"nodes"`
+}
+
+type selectorEntry struct {
+	id       int64
+	target   string
+	balancer string
+	selector selector.Selector
+}
+
+var (
+	selectorsMu   sync.RWMutex
+	selectors     = make(map[int64]*selectorEntry)
+	selectorSeq   int64
+	metricsOnce   sync.Once
+	metricsLabels = make(map[[4]string]struct{})
+)
+
+func registerSelector(target, balancerName string, s selector.Selector) int64 {
+	id := atomic.AddInt64(&selectorSeq, 1)
+	selectorsMu.Lock()
+	selectors[id] = &selectorEntry{
+		id:       id,
+		target:   target,
+		balancer: balancerName,
+		selector: s,
+	}
+	selectorsMu.Unlock()
+	metricsOnce.Do(func() {
+		go refreshSelectorMetrics()
+	})
+	return id
+}
+
+func unregisterSelector(id int64) {
+	selectorsMu.Lock()
+	delete(selectors, id)
+	selectorsMu.Unlock()
+}
+
+// SelectorSnapshots returns the runtime state of the selectors of all client connections.
+func SelectorSnapshots() []SelectorSnapshot {
+	selectorsMu.RLock()
+	entries := make([]*selectorEntry, 0, len(selectors))
+	for _, e := range selectors {
+		entries = append(entries, e)
+	}
+	selectorsMu.RUnlock()
+
+	snapshots := make([]SelectorSnapshot, 0, len(entries))
+	for _, e := range entries {
+		nodes := e.selector.Snapshot()
+		sort.Slice(nodes, func(i, j int) bool {
+			if nodes[i].ServiceName != nodes[j].ServiceName {
+				return nodes[i].ServiceName < nodes[j].ServiceName
+			}
+			return nodes[i].Address < nodes[j].Address
+		})
+		snapshots = append(snapshots, SelectorSnapshot{
+			ID:       e.id,
+			Target:   e.target,
+			Balancer: e.balancer,
+			Nodes:    nodes,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID < snapshots[j].ID })
+	return snapshots
+}
+
+// SelectorHandler returns a debug http handler which writes the selector snapshots as json,
+// the optional query parameter `target` filters client connections by dial target.
+// It can be mounted on restserver, e.g. srv.GET("/debug/selectors", gin.WrapH(rpcserver.SelectorHandler())).
+func SelectorHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshots := SelectorSnapshots()
+		if target := r.URL.Query().Get("target"); target != "" {
+			filtered := make([]SelectorSnapshot, 0, len(snapshots))
+			for _, s := range snapshots {
+				if s.Target == target {
+					filtered = append(filtered, s)
+				}
+			}
+			snapshots = filtered
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(snapshots)
+	})
+}
+
+func refreshSelectorMetrics() {
+	ticker := time.NewTicker(selectorMetricsInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		updateSelectorMetrics()
+	}
+}
+
+func updateSelectorMetrics() {
+	current := make(map[[4]string]struct{})
+	for _, s := range SelectorSnapshots() {
+		conn := strconv.FormatInt(s.ID, 10)
+		for _, n := range s.Nodes {
+			labels := [4]string{conn, s.Target, n.ServiceName, n.Address}
+			current[labels] = struct{}{}
+			metricNodeWeight.Set(n.Weight, labels[:]...)
+			metricNodeLag.Set(float64(n.Lag)/float64(time.Millisecond), labels[:]...)
+			metricNodeSuccess.Set(n.SuccessRate, labels[:]...)
+			metricNodeInflight.Set(float64(n.Inflight), labels[:]...)
+			if !n.LastPick.IsZero() {
+				metricNodeLastPick.Set(float64(n.LastPick.UnixNano())/float64(time.Second), labels[:]...)
+			}
+		}
+	}
+	for labels := range metricsLabels {
+		if _, ok := current[labels]; ok {
+			continue
+		}
+		metricNodeWeight.Delete(labels[:]...)
+		metricNodeLag.Delete(labels[:]...)
+		metricNodeSuccess.Delete(labels[:]...)
+		metricNodeInflight.Delete(labels[:]...)
+		metricNodeLastPick.Delete(labels[:]...)
+	}
+	metricsLabels = current
+}
+
+// selectorBalancer unregisters the selector of the client connection when closed.
+type selectorBalancer struct {
+	balancer.Balancer
+	id int64
+}
+
+func (b *selectorBalancer) ExitIdle() {
+	if ei, ok := b.Balancer.(balancer.ExitIdler); ok {
+		ei.ExitIdle()
+	}
+}
+
+func (b *selectorBalancer) Close() {
+	b.Balancer.Close()
+	unregisterSelector(b.id)
+}
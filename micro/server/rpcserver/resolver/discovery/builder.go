@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"google.golang.org/grpc/resolver"
+	"mymicro/micro/core/clock"
 	"mymicro/micro/registry"
 	"strings"
 	"time"
@@ -11,12 +12,28 @@ import (
 
 const name = "discovery"
 
+// EmptyPolicy decides what the resolver does when the registry returns no instance.
+type EmptyPolicy int
+
+const (
+	// EmptyPolicyKeep keeps the last known instances.
+	EmptyPolicyKeep EmptyPolicy = iota
+	// EmptyPolicyClear clears the instances immediately.
+	EmptyPolicyClear
+	// EmptyPolicyGrace keeps the last known instances for a grace period, then clears them.
+	EmptyPolicyGrace
+)
+
 type Option func(b *builder)
 
 type builder struct {
-	discoverer registry.Discovery
-	timeout    time.Duration
-	insecure   bool
+	discoverer  registry.Discovery
+	timeout     time.Duration
+	insecure    bool
+	debounce    time.Duration
+	emptyPolicy EmptyPolicy
+	emptyGrace  time.Duration
+	clock       clock.Clock
 }
 
 func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
@@ -24,10 +41,11 @@ func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts res
 		err error
 		w   registry.Watcher
 	)
+	serviceName := strings.TrimPrefix(target.URL.Path, "/")
 	done := make(chan struct{}, 1)
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
-		w, err = b.discoverer.Watch(ctx, strings.TrimPrefix(target.URL.Path, "/"))
+		w, err = b.discoverer.Watch(ctx, serviceName)
 		close(done)
 	}()
 	select {
@@ -40,12 +58,22 @@ func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts res
 		return nil, err
 	}
 	r := &discoveryResolver{
-		w:        w,
-		cc:       cc,
-		cancel:   cancel,
-		insecure: b.insecure,
+		w:           w,
+		cc:          cc,
+		ctx:         ctx,
+		cancel:      cancel,
+		insecure:    b.insecure,
+		discoverer:  b.discoverer,
+		serviceName: serviceName,
+		timeout:     b.timeout,
+		debounce:    b.debounce,
+		emptyPolicy: b.emptyPolicy,
+		emptyGrace:  b.emptyGrace,
+		clock:       b.clock,
+		resolveNow:  make(chan struct{}, 1),
 	}
 	go r.watch()
+	go r.resolveLoop()
 	return r, nil
 }
 
@@ -55,9 +83,12 @@ func (b *builder) Scheme() string {
 
 func NewBuilder(d registry.Discovery, opts ...Option) resolver.Builder {
 	b := &builder{
-		discoverer: d,
-		timeout:    time.Second * 10,
-		insecure:   false,
+		discoverer:  d,
+		timeout:     time.Second * 10,
+		insecure:    false,
+		emptyPolicy: EmptyPolicyKeep,
+		emptyGrace:  time.Second * 30,
+		clock:       clock.Real(),
 	}
 	for _, opt := range opts {
 		opt(b)
@@ -76,3 +107,32 @@ func WithInsecure(insecure bool) Option {
 		b.insecure = insecure
 	}
 }
+
+// WithDebounce coalesces the registry changes within the window into one update,
+// zero disables debounce.
+func WithDebounce(debounce time.Duration) Option {
+	return func(b *builder) {
+		b.debounce = debounce
+	}
+}
+
+// WithEmptyPolicy sets the policy for an empty instance list.
+func WithEmptyPolicy(policy EmptyPolicy) Option {
+	return func(b *builder) {
+		b.emptyPolicy = policy
+	}
+}
+
+// WithEmptyGracePeriod sets how long the last known instances are kept with EmptyPolicyGrace.
+func WithEmptyGracePeriod(grace time.Duration) Option {
+	return func(b *builder) {
+		b.emptyGrace = grace
+	}
+}
+
+// WithClock sets the clock waiting for the debounce window and the empty grace period, the real clock by default.
+func WithClock(c clock.Clock) Option {
+	return func(b *builder) {
+		b.clock = c
+	}
+}
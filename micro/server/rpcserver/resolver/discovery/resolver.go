@@ -6,10 +6,12 @@ import (
 	"errors"
 	"google.golang.org/grpc/attributes"
 	"google.golang.org/grpc/resolver"
+	"mymicro/micro/core/clock"
 	"mymicro/micro/registry"
 	"mymicro/pkg/log"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -20,6 +22,23 @@ type discoveryResolver struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
 	insecure bool
+
+	discoverer  registry.Discovery
+	serviceName string
+	timeout     time.Duration
+	resolveNow  chan struct{}
+
+	debounce    time.Duration
+	emptyPolicy EmptyPolicy
+	emptyGrace  time.Duration
+	clock       clock.Clock
+
+	updateMu   sync.Mutex
+	mu         sync.Mutex
+	pending    []*registry.ServiceInstance
+	debouncing bool
+	emptySince time.Time
+	graceStop  chan struct{}
 }
 
 func (r *discoveryResolver) watch() {
@@ -35,14 +54,74 @@ func (r *discoveryResolver) watch() {
 				return
 			}
 			log.Errorf("[resolver] Failed to watch discovery endpoint: %v", err)
+			r.cc.ReportError(err)
 			time.Sleep(time.Second)
 			continue
 		}
+		r.push(ins)
+	}
+}
+
+// resolveLoop fetches the instances from the registry when ResolveNow is called.
+func (r *discoveryResolver) resolveLoop() {
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.resolveNow:
+		}
+		ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+		ins, err := r.discoverer.GetService(ctx, r.serviceName)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Errorf("[resolver] Failed to get service %s: %v", r.serviceName, err)
+			r.cc.ReportError(err)
+			continue
+		}
+		r.update(ins)
+	}
+}
+
+// push coalesces the changes within the debounce window into one update.
+func (r *discoveryResolver) push(ins []*registry.ServiceInstance) {
+	if r.debounce <= 0 {
 		r.update(ins)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = ins
+	if !r.debouncing {
+		r.debouncing = true
+		go r.flush(r.clock.After(r.debounce))
 	}
 }
 
+func (r *discoveryResolver) flush(after <-chan time.Time) {
+	select {
+	case <-r.ctx.Done():
+		return
+	case <-after:
+	}
+	r.mu.Lock()
+	ins := r.pending
+	r.pending = nil
+	r.debouncing = false
+	r.mu.Unlock()
+	r.update(ins)
+}
+
 func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+	select {
+	case <-r.ctx.Done():
+		return
+	default:
+	}
 	addrs := make([]resolver.Address, 0)
 	endpoints := make(map[string]struct{})
 	for _, in := range ins {
@@ -66,10 +145,18 @@ func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
 		addr.Attributes = addr.Attributes.WithValue("rawServiceInstance", in)
 		addrs = append(addrs, addr)
 	}
-	if len(addrs) == 0 {
-		log.Warnf("[resolver] Zero endpoint found, refused to write")
+	if len(addrs) == 0 && !r.acceptEmpty() {
 		return
 	}
+	if len(addrs) > 0 {
+		r.mu.Lock()
+		r.emptySince = time.Time{}
+		if r.graceStop != nil {
+			close(r.graceStop)
+			r.graceStop = nil
+		}
+		r.mu.Unlock()
+	}
 	err := r.cc.UpdateState(resolver.State{Addresses: addrs})
 	if err != nil {
 		log.Errorf("[resolver] Failed to update state: %s", err)
@@ -78,6 +165,70 @@ func (r *discoveryResolver) update(ins []*registry.ServiceInstance) {
 	log.Infof("[resolver] Update instances: %s", b)
 }
 
+// acceptEmpty reports whether an empty instance list should be written according to the empty policy.
+func (r *discoveryResolver) acceptEmpty() bool {
+	switch r.emptyPolicy {
+	case EmptyPolicyClear:
+		log.Warnf("[resolver] Zero endpoint found, clear instances")
+		return true
+	case EmptyPolicyGrace:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.emptySince.IsZero() {
+			r.emptySince = r.clock.Now()
+		}
+		if elapsed := r.clock.Now().Sub(r.emptySince); elapsed < r.emptyGrace {
+			log.Warnf("[resolver] Zero endpoint found, keep instances for %s", r.emptyGrace-elapsed)
+			if r.graceStop == nil {
+				r.graceStop = make(chan struct{})
+				go r.expireAfter(r.clock.After(r.emptyGrace-elapsed), r.graceStop)
+			}
+			return false
+		}
+		log.Warnf("[resolver] Zero endpoint found after %s, clear instances", r.emptyGrace)
+		return true
+	default:
+		log.Warnf("[resolver] Zero endpoint found, refused to write")
+		return false
+	}
+}
+
+// expireAfter clears the instances when the grace period elapses, unless they are refilled or the resolver is closed before.
+func (r *discoveryResolver) expireAfter(after <-chan time.Time, stop chan struct{}) {
+	select {
+	case <-r.ctx.Done():
+		return
+	case <-stop:
+		return
+	case <-after:
+	}
+	r.expire(stop)
+}
+
+// expire writes the empty instance list directly, the registry may still return the stale instances or an error
+// and never confirm the empty list again. It does nothing if the grace period of stop is already over.
+func (r *discoveryResolver) expire(stop chan struct{}) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+	select {
+	case <-r.ctx.Done():
+		return
+	default:
+	}
+	r.mu.Lock()
+	if r.graceStop != stop {
+		r.mu.Unlock()
+		return
+	}
+	r.graceStop = nil
+	r.mu.Unlock()
+	log.Warnf("[resolver] Zero endpoint found after %s, clear instances", r.emptyGrace)
+	err := r.cc.UpdateState(resolver.State{Addresses: []resolver.Address{}})
+	if err != nil {
+		log.Errorf("[resolver] Failed to update state: %s", err)
+	}
+}
+
 func (r *discoveryResolver) Close() {
 	r.cancel()
 	err := r.w.Stop()
@@ -86,7 +237,13 @@ func (r *discoveryResolver) Close() {
 	}
 }
 
-func (r *discoveryResolver) ResolveNow(options resolver.ResolveNowOptions) {}
+// ResolveNow triggers an immediate refresh from the registry.
+func (r *discoveryResolver) ResolveNow(options resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default:
+	}
+}
 
 func parseAttributes(md map[string]string) *attributes.Attributes {
 	var a *attributes.Attributes
@@ -0,0 +1,248 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+
+	"mymicro/micro/registry"
+)
+
+type testWatcher struct {
+	ch    chan []*registry.ServiceInstance
+	ready chan struct{}
+	ctx   context.Context
+	once  sync.Once
+}
+
+func (w *testWatcher) Next() ([]*registry.ServiceInstance, error) {
+	// 除首次外，Next 被再次调用说明上一次的变更已经处理完
+	first := false
+	w.once.Do(func() { first = true })
+	if !first {
+		w.ready <- struct{}{}
+	}
+	select {
+	case ins := <-w.ch:
+		return ins, nil
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	}
+}
+
+func (w *testWatcher) Stop() error { return nil }
+
+type testDiscovery struct {
+	mu  sync.Mutex
+	ins []*registry.ServiceInstance
+	w   *testWatcher
+}
+
+func (d *testDiscovery) GetService(_ context.Context, _ string) ([]*registry.ServiceInstance, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ins, nil
+}
+
+func (d *testDiscovery) Watch(ctx context.Context, _ string) (registry.Watcher, error) {
+	d.w = &testWatcher{
+		ch:    make(chan []*registry.ServiceInstance),
+		ready: make(chan struct{}, 1),
+		ctx:   ctx,
+	}
+	return d.w, nil
+}
+
+// send pushes the change to the resolver and waits until it is handled.
+func (d *testDiscovery) send(ins ...*registry.ServiceInstance) {
+	d.w.ch <- ins
+	<-d.w.ready
+}
+
+type testClientConn struct {
+	mu      sync.Mutex
+	states  []resolver.State
+	errs    []error
+	updated chan struct{}
+}
+
+func newTestClientConn() *testClientConn {
+	return &testClientConn{updated: make(chan struct{}, 16)}
+}
+
+func (cc *testClientConn) UpdateState(s resolver.State) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.states = append(cc.states, s)
+	cc.updated <- struct{}{}
+	return nil
+}
+
+func (cc *testClientConn) ReportError(err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.errs = append(cc.errs, err)
+}
+
+func (cc *testClientConn) NewAddress(_ []resolver.Address) {}
+
+func (cc *testClientConn) ParseServiceConfig(_ string) *serviceconfig.ParseResult { return nil }
+
+func (cc *testClientConn) updates() []resolver.State {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return append([]resolver.State(nil), cc.states...)
+}
+
+// wait blocks until n updates are written.
+func (cc *testClientConn) wait(t *testing.T, n int) {
+	t.Helper()
+	for len(cc.updates()) < n {
+		select {
+		case <-cc.updated:
+		case <-time.After(time.Second * 5):
+			t.Fatalf("expect %d updates, got %d", n, len(cc.updates()))
+		}
+	}
+}
+
+// testClock hands the timers to the test, which fires them by sending on the returned channel.
+type testClock struct {
+	timers chan chan time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{timers: make(chan chan time.Time, 16)}
+}
+
+func (c *testClock) Now() time.Time { return time.Now() }
+
+func (c *testClock) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.timers <- ch
+	return ch
+}
+
+// fire fires the next timer started by the resolver.
+func (c *testClock) fire() {
+	(<-c.timers) <- time.Now()
+}
+
+func instance(id, endpoint string) *registry.ServiceInstance {
+	return &registry.ServiceInstance{ID: id, Name: "helloworld", Endpoints: []string{endpoint}}
+}
+
+func build(t *testing.T, d *testDiscovery, cc *testClientConn, opts ...Option) resolver.Resolver {
+	r, err := NewBuilder(d, append([]Option{WithInsecure(true)}, opts...)...).
+		Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatalf("build resolver: %v", err)
+	}
+	return r
+}
+
+func TestDebounce(t *testing.T) {
+	d := &testDiscovery{}
+	cc := newTestClientConn()
+	clk := newTestClock()
+	r := build(t, d, cc, WithDebounce(time.Millisecond*50), WithClock(clk))
+	defer r.Close()
+
+	d.send(instance("1", "grpc://127.0.0.1:9000"))
+	d.send(instance("1", "grpc://127.0.0.1:9000"), instance("2", "grpc://127.0.0.1:9001"))
+	if states := cc.updates(); len(states) != 0 {
+		t.Fatalf("expect no update within the debounce window, got %d", len(states))
+	}
+	clk.fire()
+	cc.wait(t, 1)
+	states := cc.updates()
+	if len(states) != 1 {
+		t.Fatalf("expect 1 update, got %d", len(states))
+	}
+	if len(states[0].Addresses) != 2 {
+		t.Errorf("expect 2 addresses, got %d", len(states[0].Addresses))
+	}
+}
+
+func TestResolveNow(t *testing.T) {
+	d := &testDiscovery{ins: []*registry.ServiceInstance{instance("1", "grpc://127.0.0.1:9000")}}
+	cc := newTestClientConn()
+	r := build(t, d, cc)
+	defer r.Close()
+
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	cc.wait(t, 1)
+	if states := cc.updates(); len(states) != 1 || len(states[0].Addresses) != 1 {
+		t.Errorf("expect 1 update with 1 address, got %v", states)
+	}
+}
+
+func TestEmptyPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		expect int
+	}{
+		{name: "keep", opts: []Option{WithEmptyPolicy(EmptyPolicyKeep)}, expect: 1},
+		{name: "clear", opts: []Option{WithEmptyPolicy(EmptyPolicyClear)}, expect: 2},
+		{name: "grace", opts: []Option{
+			WithEmptyPolicy(EmptyPolicyGrace),
+			WithEmptyGracePeriod(time.Millisecond * 50),
+		}, expect: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &testDiscovery{}
+			cc := newTestClientConn()
+			clk := newTestClock()
+			r := build(t, d, cc, append(tt.opts, WithClock(clk))...)
+			defer r.Close()
+
+			d.send(instance("1", "grpc://127.0.0.1:9000"))
+			d.send()
+			if tt.name == "grace" {
+				if states := cc.updates(); len(states) != 1 {
+					t.Errorf("expect instances kept during grace period, got %d updates", len(states))
+				}
+				// 注册中心不再返回空列表，宽限期结束后也要清空
+				clk.fire()
+				cc.wait(t, 2)
+			}
+			states := cc.updates()
+			if len(states) != tt.expect {
+				t.Fatalf("expect %d updates, got %d", tt.expect, len(states))
+			}
+			if len(states[len(states)-1].Addresses) != 2-tt.expect {
+				t.Errorf("unexpected addresses %v", states[len(states)-1].Addresses)
+			}
+		})
+	}
+}
+
+func TestEmptyGraceRefilled(t *testing.T) {
+	d := &testDiscovery{}
+	cc := newTestClientConn()
+	clk := newTestClock()
+	r := build(t, d, cc, WithEmptyPolicy(EmptyPolicyGrace), WithClock(clk))
+	defer r.Close()
+
+	d.send(instance("1", "grpc://127.0.0.1:9000"))
+	d.send()
+	dr := r.(*discoveryResolver)
+	dr.mu.Lock()
+	stop := dr.graceStop
+	dr.mu.Unlock()
+	d.send(instance("1", "grpc://127.0.0.1:9000"))
+	dr.expire(stop)
+	states := cc.updates()
+	if len(states) != 2 {
+		t.Fatalf("expect 2 updates, got %d", len(states))
+	}
+	if len(states[1].Addresses) != 1 {
+		t.Errorf("expect instances refilled, got %v", states[1].Addresses)
+	}
+}
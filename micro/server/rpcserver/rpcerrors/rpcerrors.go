@@ -0,0 +1,167 @@
+package rpcerrors
+
+import (
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/pkg/errors"
+)
+
+// Domain is the domain of the ErrorInfo details carrying the pkg/errors codes.
+const Domain = "mymicro"
+
+// Keys of the ErrorInfo metadata.
+const (
+	MetadataCode       = "code"
+	MetadataMessage    = "message"
+	MetadataReference  = "reference"
+	MetadataHTTPStatus = "http_status"
+)
+
+// ToStatus converts an error with code of pkg/errors into a status error,
+// the code, message and reference of its coder are carried by an ErrorInfo detail.
+// Status errors and the errors without code are returned as is, so are the errors rebuilt by FromStatus
+// whose code is not changed. An error re-coded by a middle hop gets the status of its outermost code,
+// instead of the status of the downstream error it wraps.
+func ToStatus(err error) error {
+	if err == nil || !errors.HasCode(err) {
+		return err
+	}
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+
+	coder := errors.ParseCoder(err)
+	if st, ok := status.FromError(err); ok {
+		if info := errorInfo(st); info != nil && info.GetMetadata()[MetadataCode] == strconv.Itoa(coder.Code()) {
+			return err
+		}
+	}
+	st := status.New(GRPCCode(coder.HTTPStatus()), err.Error())
+	detailed, derr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: strconv.Itoa(coder.Code()),
+		Domain: Domain,
+		Metadata: map[string]string{
+			MetadataCode:       strconv.Itoa(coder.Code()),
+			MetadataMessage:    coder.String(),
+			MetadataReference:  coder.Reference(),
+			MetadataHTTPStatus: strconv.Itoa(coder.HTTPStatus()),
+		},
+	})
+	if derr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// FromStatus rebuilds the error with code of pkg/errors from a status error converted by ToStatus,
+// so that errors.IsCode and errors.ParseCoder work across services.
+// The status error is kept as the cause, status.Code still works on the rebuilt error.
+// The other errors are returned as is.
+func FromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+	info := errorInfo(st)
+	if info == nil {
+		return err
+	}
+	md := info.GetMetadata()
+	code, cerr := strconv.Atoi(md[MetadataCode])
+	if cerr != nil {
+		return err
+	}
+	httpStatus, herr := strconv.Atoi(md[MetadataHTTPStatus])
+	if herr != nil {
+		httpStatus = HTTPStatus(st.Code())
+	}
+	coder := errors.NewCoder(code, httpStatus, md[MetadataMessage], md[MetadataReference])
+	return errors.WrapCoder(err, coder, "%s", st.Message())
+}
+
+// errorInfo returns the ErrorInfo detail of Domain in the status, nil if not found.
+func errorInfo(st *status.Status) *errdetails.ErrorInfo {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == Domain {
+			return info
+		}
+	}
+	return nil
+}
+
+// GRPCCode returns the gRPC code of the HTTP status, the unmapped 4xx statuses are FailedPrecondition
+// and the unmapped 5xx statuses are Internal.
+func GRPCCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	// 未映射的状态按类别转换，业务4xx不能被熔断器当作失败
+	switch {
+	case httpStatus >= 400 && httpStatus < 500:
+		return codes.FailedPrecondition
+	case httpStatus >= 500:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// HTTPStatus returns the HTTP status of the gRPC code.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
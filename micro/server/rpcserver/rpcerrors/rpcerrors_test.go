@@ -0,0 +1,85 @@
+package rpcerrors
+
+import (
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/pkg/errors"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	const code = 100404
+	coder := errors.NewCoder(code, http.StatusNotFound, "User not found", "http://docs/user")
+
+	// the code is registered on the server only
+	err := ToStatus(errors.WrapCoder(errors.New("record not found"), coder, "user %d not found", 1))
+	if c := status.Code(err); c != codes.NotFound {
+		t.Errorf("expect %v, got %v", codes.NotFound, c)
+	}
+
+	err = FromStatus(err)
+	if !errors.IsCode(err, code) {
+		t.Errorf("expect code %d, got %v", code, err)
+	}
+	got := errors.ParseCoder(err)
+	if got.Code() != code || got.HTTPStatus() != http.StatusNotFound ||
+		got.String() != "User not found" || got.Reference() != "http://docs/user" {
+		t.Errorf("expect %+v, got %+v", coder, got)
+	}
+	if c := status.Code(err); c != codes.NotFound {
+		t.Errorf("expect %v kept, got %v", codes.NotFound, c)
+	}
+
+	// the rebuilt error is passed through the next hop
+	if next := ToStatus(err); status.Code(next) != codes.NotFound || !errors.IsCode(FromStatus(next), code) {
+		t.Errorf("expect code %d across hops, got %v", code, next)
+	}
+}
+
+func TestStatusPassThrough(t *testing.T) {
+	plain := errors.New("plain")
+	if err := ToStatus(plain); err != plain {
+		t.Errorf("expect %v, got %v", plain, err)
+	}
+	st := status.Error(codes.Unavailable, "unavailable")
+	if err := FromStatus(st); err != st {
+		t.Errorf("expect %v, got %v", st, err)
+	}
+}
+
+func TestStatusRecoded(t *testing.T) {
+	const recoded = 222002
+	errors.Register(errors.NewCoder(recoded, http.StatusBadRequest, "Invalid user", ""))
+	downstream := errors.NewCoder(111001, http.StatusNotFound, "User not found", "")
+	err := FromStatus(ToStatus(errors.WrapCoder(errors.New("record not found"), downstream, "user not found")))
+
+	// the middle hop re-codes the downstream error
+	err = ToStatus(errors.WrapC(err, recoded, "invalid user"))
+	if c := status.Code(err); c != codes.InvalidArgument {
+		t.Errorf("expect %v, got %v", codes.InvalidArgument, c)
+	}
+	if got := errors.ParseCoder(FromStatus(err)).Code(); got != recoded {
+		t.Errorf("expect code %d, got %d", recoded, got)
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	tests := []struct {
+		httpStatus int
+		code       codes.Code
+	}{
+		{httpStatus: http.StatusNotFound, code: codes.NotFound},
+		{httpStatus: http.StatusUnprocessableEntity, code: codes.FailedPrecondition},
+		{httpStatus: http.StatusGone, code: codes.FailedPrecondition},
+		{httpStatus: http.StatusBadGateway, code: codes.Internal},
+		{httpStatus: http.StatusMovedPermanently, code: codes.Unknown},
+	}
+	for _, tt := range tests {
+		if c := GRPCCode(tt.httpStatus); c != tt.code {
+			t.Errorf("%d: expect %v, got %v", tt.httpStatus, tt.code, c)
+		}
+	}
+}
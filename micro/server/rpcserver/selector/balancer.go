@@ -30,6 +30,9 @@ type WeightedNode interface {
 
 	// PickElapsed is time elapsed since the latest pick
 	PickElapsed() time.Duration
+
+	// Snapshot returns the runtime state of the node
+	Snapshot() NodeSnapshot
 }
 
 // WeightedNodeBuilder is WeightedNode Builder
@@ -28,6 +28,9 @@ func (d *Default) Select(ctx context.Context) (selected Node, done DoneFunc, err
 		return nil, nil, ErrNoAvailable
 	}
 	candidates = nodes
+	if filters := FiltersFromContext(ctx); len(filters) > 0 {
+		candidates = filterNodes(ctx, filters, nodes)
+	}
 
 	if len(candidates) == 0 {
 		return nil, nil, ErrNoAvailable
@@ -40,19 +43,64 @@ func (d *Default) Select(ctx context.Context) (selected Node, done DoneFunc, err
 	if ok {
 		p.Node = wn.Raw()
 	}
+	if fn, ok := pickCallbackFromContext(ctx); ok {
+		fn(wn.Raw())
+	}
 	return wn.Raw(), done, nil
 }
 
 // Apply update nodes info.
+// Unchanged nodes are kept so that their runtime statistics survive the update.
 func (d *Default) Apply(nodes []Node) {
+	old := make(map[string]WeightedNode)
+	if prev, ok := d.nodes.Load().([]WeightedNode); ok {
+		for _, wn := range prev {
+			old[wn.Address()] = wn
+		}
+	}
 	weightedNodes := make([]WeightedNode, 0, len(nodes))
 	for _, n := range nodes {
+		if wn, ok := old[n.Address()]; ok && sameNode(wn.Raw(), n) {
+			weightedNodes = append(weightedNodes, wn)
+			continue
+		}
 		weightedNodes = append(weightedNodes, d.NodeBuilder.Build(n))
 	}
-	// TODO: Do not delete unchanged nodes
 	d.nodes.Store(weightedNodes)
 }
 
+// Snapshot returns the runtime state of all nodes.
+func (d *Default) Snapshot() []NodeSnapshot {
+	nodes, _ := d.nodes.Load().([]WeightedNode)
+	snapshots := make([]NodeSnapshot, 0, len(nodes))
+	for _, wn := range nodes {
+		snapshots = append(snapshots, wn.Snapshot())
+	}
+	return snapshots
+}
+
+// sameNode reports whether a and b describe the same service instance.
+func sameNode(a, b Node) bool {
+	if a.Scheme() != b.Scheme() || a.Address() != b.Address() ||
+		a.ServiceName() != b.ServiceName() || a.Version() != b.Version() {
+		return false
+	}
+	wa, wb := a.InitialWeight(), b.InitialWeight()
+	if (wa == nil) != (wb == nil) || (wa != nil && *wa != *wb) {
+		return false
+	}
+	ma, mb := a.Metadata(), b.Metadata()
+	if len(ma) != len(mb) {
+		return false
+	}
+	for k, v := range ma {
+		if bv, ok := mb[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
 // DefaultBuilder is de
 type DefaultBuilder struct {
 	Node     WeightedNodeBuilder
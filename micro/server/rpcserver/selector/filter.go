@@ -0,0 +1,60 @@
+package selector
+
+import (
+	"context"
+)
+
+type (
+	filterKey       struct{}
+	pickCallbackKey struct{}
+)
+
+// NodeFilter filters the candidate nodes before picking.
+type NodeFilter func(ctx context.Context, nodes []Node) []Node
+
+// NewFilterContext creates a new context with node filters attached,
+// filters are applied in order after the ones already in ctx.
+func NewFilterContext(ctx context.Context, filters ...NodeFilter) context.Context {
+	if prev, ok := ctx.Value(filterKey{}).([]NodeFilter); ok {
+		filters = append(append([]NodeFilter(nil), prev...), filters...)
+	}
+	return context.WithValue(ctx, filterKey{}, filters)
+}
+
+// FiltersFromContext returns the node filters in ctx if they exist.
+func FiltersFromContext(ctx context.Context) []NodeFilter {
+	filters, _ := ctx.Value(filterKey{}).([]NodeFilter)
+	return filters
+}
+
+// NewPickCallbackContext creates a new context whose fn is called with the selected node after each pick.
+func NewPickCallbackContext(ctx context.Context, fn func(Node)) context.Context {
+	return context.WithValue(ctx, pickCallbackKey{}, fn)
+}
+
+func pickCallbackFromContext(ctx context.Context) (func(Node), bool) {
+	fn, ok := ctx.Value(pickCallbackKey{}).(func(Node))
+	return fn, ok
+}
+
+// filterNodes applies the filters to the weighted nodes.
+func filterNodes(ctx context.Context, filters []NodeFilter, nodes []WeightedNode) []WeightedNode {
+	raw := make([]Node, 0, len(nodes))
+	for _, wn := range nodes {
+		raw = append(raw, wn)
+	}
+	for _, f := range filters {
+		raw = f(ctx, raw)
+	}
+	kept := make(map[string]struct{}, len(raw))
+	for _, n := range raw {
+		kept[n.Address()] = struct{}{}
+	}
+	candidates := make([]WeightedNode, 0, len(raw))
+	for _, wn := range nodes {
+		if _, ok := kept[wn.Address()]; ok {
+			candidates = append(candidates, wn)
+		}
+	}
+	return candidates
+}
@@ -23,14 +23,26 @@ type Node struct {
 
 	// last lastPick timestamp
 	lastPick int64
+	// first appearance timestamp
+	born int64
+
+	slowStart time.Duration
 }
 
 // Builder is direct node builder
-type Builder struct{}
+type Builder struct {
+	// SlowStart is the window over which the weight of a new node ramps up to full
+	SlowStart time.Duration
+}
 
 // Build create node
-func (*Builder) Build(n selector.Node) selector.WeightedNode {
-	return &Node{Node: n, lastPick: 0}
+func (b *Builder) Build(n selector.Node) selector.WeightedNode {
+	return &Node{
+		Node:      n,
+		lastPick:  0,
+		born:      time.Now().UnixNano(),
+		slowStart: b.SlowStart,
+	}
 }
 
 func (n *Node) Pick() selector.DoneFunc {
@@ -41,10 +53,11 @@ func (n *Node) Pick() selector.DoneFunc {
 
 // Weight is node effective weight
 func (n *Node) Weight() float64 {
+	weight := float64(defaultWeight)
 	if n.InitialWeight() != nil {
-		return float64(*n.InitialWeight())
+		weight = float64(*n.InitialWeight())
 	}
-	return defaultWeight
+	return weight * selector.SlowStartFactor(n.born, n.slowStart, time.Now().UnixNano())
 }
 
 func (n *Node) PickElapsed() time.Duration {
@@ -54,3 +67,20 @@ func (n *Node) PickElapsed() time.Duration {
 func (n *Node) Raw() selector.Node {
 	return n.Node
 }
+
+// Snapshot returns the runtime state of the node.
+func (n *Node) Snapshot() selector.NodeSnapshot {
+	var lastPick time.Time
+	if pick := atomic.LoadInt64(&n.lastPick); pick > 0 {
+		lastPick = time.Unix(0, pick)
+	}
+	return selector.NodeSnapshot{
+		Scheme:      n.Scheme(),
+		Address:     n.Address(),
+		ServiceName: n.ServiceName(),
+		Version:     n.Version(),
+		Weight:      n.Weight(),
+		SuccessRate: 1,
+		LastPick:    lastPick,
+	}
+}
@@ -2,6 +2,7 @@ package direct
 
 import (
 	"context"
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -68,3 +69,37 @@ func TestDirectDefaultWeight(t *testing.T) {
 		t.Errorf("time.Millisecond*5 >= wn.PickElapsed()(%s)", wn.PickElapsed())
 	}
 }
+
+func TestDirectSlowStart(t *testing.T) {
+	b := &Builder{SlowStart: time.Minute}
+	tests := []struct {
+		name    string
+		md      map[string]string
+		elapsed time.Duration
+		weight  float64
+	}{
+		{name: "born", md: map[string]string{"weight": "10"}, weight: 1},
+		{name: "half", md: map[string]string{"weight": "10"}, elapsed: 30 * time.Second, weight: 5.5},
+		{name: "full", md: map[string]string{"weight": "10"}, elapsed: time.Minute, weight: 10},
+		{name: "default weight", elapsed: 30 * time.Second, weight: 55},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := b.Build(selector.NewNode(
+				"http",
+				"127.0.0.1:9090",
+				&registry.ServiceInstance{
+					ID:        "127.0.0.1:9090",
+					Name:      "helloworld",
+					Version:   "v1.0.0",
+					Endpoints: []string{"http://127.0.0.1:9090"},
+					Metadata:  tt.md,
+				})).(*Node)
+			// 调整出现时间代替等待
+			n.born = time.Now().Add(-tt.elapsed).UnixNano()
+			if w := n.Weight(); math.Abs(w-tt.weight) > 0.01 {
+				t.Errorf("expect %v, got %v", tt.weight, w)
+			}
+		})
+	}
+}
@@ -38,7 +38,10 @@ type Node struct {
 	reqs int64
 	// last lastPick timestamp
 	lastPick int64
+	// first appearance timestamp
+	born int64
 
+	slowStart    time.Duration
 	errHandler   func(err error) (isErr bool)
 	cachedWeight *atomic.Value
 }
@@ -51,6 +54,8 @@ type nodeWeight struct {
 // Builder is ewma node builder.
 type Builder struct {
 	ErrHandler func(err error) (isErr bool)
+	// SlowStart is the window over which the weight of a new node ramps up to full
+	SlowStart time.Duration
 }
 
 // Build create a weighted node.
@@ -60,6 +65,8 @@ func (b *Builder) Build(n selector.Node) selector.WeightedNode {
 		lag:          0,
 		success:      1000,
 		inflight:     1,
+		born:         time.Now().UnixNano(),
+		slowStart:    b.SlowStart,
 		errHandler:   b.ErrHandler,
 		cachedWeight: &atomic.Value{},
 	}
@@ -172,6 +179,7 @@ func (n *Node) Weight() (weight float64) {
 		health := n.health()
 		load := n.load()
 		weight = float64(health*uint64(time.Microsecond)*10) / float64(load)
+		weight *= selector.SlowStartFactor(n.born, n.slowStart, now)
 		n.cachedWeight.Store(&nodeWeight{
 			value:    weight,
 			updateAt: now,
@@ -189,3 +197,23 @@ func (n *Node) PickElapsed() time.Duration {
 func (n *Node) Raw() selector.Node {
 	return n.Node
 }
+
+// Snapshot returns the runtime state of the node.
+func (n *Node) Snapshot() selector.NodeSnapshot {
+	var lastPick time.Time
+	if pick := atomic.LoadInt64(&n.lastPick); pick > 0 {
+		lastPick = time.Unix(0, pick)
+	}
+	return selector.NodeSnapshot{
+		Scheme:      n.Scheme(),
+		Address:     n.Address(),
+		ServiceName: n.ServiceName(),
+		Version:     n.Version(),
+		Weight:      n.Weight(),
+		Lag:         time.Duration(atomic.LoadInt64(&n.lag)),
+		SuccessRate: float64(n.health()) / 1000,
+		// inflight starts from 1 to avoid zero load
+		Inflight: atomic.LoadInt64(&n.inflight) - 1,
+		LastPick: lastPick,
+	}
+}
@@ -2,6 +2,7 @@ package ewma
 
 import (
 	"context"
+	"math"
 	"net"
 	"reflect"
 	"testing"
@@ -147,3 +148,35 @@ func BenchmarkPickAndWeight(b *testing.B) {
 		}
 	})
 }
+
+func TestSlowStart(t *testing.T) {
+	b := &Builder{SlowStart: time.Minute}
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		weight  float64
+	}{
+		{name: "born", weight: 10},
+		{name: "half", elapsed: 30 * time.Second, weight: 55},
+		{name: "full", elapsed: time.Minute, weight: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := b.Build(selector.NewNode(
+				"http",
+				"127.0.0.1:9090",
+				&registry.ServiceInstance{
+					ID:        "127.0.0.1:9090",
+					Name:      "helloworld",
+					Version:   "v1.0.0",
+					Endpoints: []string{"http://127.0.0.1:9090"},
+					Metadata:  map[string]string{"weight": "10"},
+				})).(*Node)
+			// 调整出现时间代替等待
+			n.born = time.Now().Add(-tt.elapsed).UnixNano()
+			if w := n.Weight(); math.Abs(w-tt.weight) > 0.01 {
+				t.Errorf("expect %v, got %v", tt.weight, w)
+			}
+		})
+	}
+}
@@ -23,7 +23,16 @@ var _ selector.Balancer = (*Balancer)(nil)
 type Option func(o *options)
 
 // options is p2c builder options
-type options struct{}
+type options struct {
+	slowStart time.Duration
+}
+
+// WithSlowStart sets the window over which the weight of a new node ramps up to full.
+func WithSlowStart(d time.Duration) Option {
+	return func(o *options) {
+		o.slowStart = d
+	}
+}
 
 // New creates a p2c selector.
 func New(opts ...Option) selector.Selector {
@@ -87,7 +96,7 @@ func NewBuilder(opts ...Option) selector.Builder {
 	}
 	return &selector.DefaultBuilder{
 		Balancer: &Builder{},
-		Node:     &ewma.Builder{},
+		Node:     &ewma.Builder{SlowStart: option.slowStart},
 	}
 }
 
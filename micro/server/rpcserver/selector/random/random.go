@@ -3,6 +3,7 @@ package random
 import (
 	"context"
 	"math/rand"
+	"time"
 
 	"mymicro/micro/server/rpcserver/selector"
 	"mymicro/micro/server/rpcserver/selector/node/direct"
@@ -19,7 +20,16 @@ var _ selector.Balancer = (*Balancer)(nil) // Name is balancer name
 type Option func(o *options)
 
 // options is random builder options
-type options struct{}
+type options struct {
+	slowStart time.Duration
+}
+
+// WithSlowStart sets the window over which the weight of a new node ramps up to full.
+func WithSlowStart(d time.Duration) Option {
+	return func(o *options) {
+		o.slowStart = d
+	}
+}
 
 // Balancer is a random balancer.
 type Balancer struct{}
@@ -48,7 +58,7 @@ func NewBuilder(opts ...Option) selector.Builder {
 	}
 	return &selector.DefaultBuilder{
 		Balancer: &Builder{},
-		Node:     &direct.Builder{},
+		Node:     &direct.Builder{SlowStart: option.slowStart},
 	}
 }
 
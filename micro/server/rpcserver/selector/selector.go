@@ -3,6 +3,7 @@ package selector
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 // ErrNoAvailable is no available node.
@@ -15,6 +16,9 @@ type Selector interface {
 	// Select nodes
 	// if err == nil, selected and done must not be empty.
 	Select(ctx context.Context) (selected Node, done DoneFunc, err error)
+
+	// Snapshot returns the runtime state of all nodes, it must not change the selector
+	Snapshot() []NodeSnapshot
 }
 
 // Rebalancer is nodes rebalancer.
@@ -71,3 +75,21 @@ type ReplyMD interface {
 
 // DoneFunc is callback function when RPC invoke done.
 type DoneFunc func(ctx context.Context, di DoneInfo)
+
+// NodeSnapshot is a read-only view of the runtime state of a weighted node.
+type NodeSnapshot struct {
+	Scheme      string `json:"scheme"`
+	Address     string `json:"address"`
+	ServiceName string `json:"service_name"`
+	Version     string `json:"version"`
+	// Weight is the runtime calculated weight
+	Weight float64 `json:"weight"`
+	// Lag is the moving average of request latency
+	Lag time.Duration `json:"lag"`
+	// SuccessRate is the moving average of successful requests, in [0, 1]
+	SuccessRate float64 `json:"success_rate"`
+	// Inflight is the number of requests in progress
+	Inflight int64 `json:"inflight"`
+	// LastPick is the time of the latest pick, zero if never picked
+	LastPick time.Time `json:"last_pick"`
+}
@@ -0,0 +1,24 @@
+package selector
+
+import "time"
+
+// SlowStartMinFactor is the fraction of its weight a node starts with
+// when it first appears during a slow start window.
+const SlowStartMinFactor = 0.1
+
+// SlowStartFactor returns the ratio applied to a node's weight during slow start.
+// The ratio grows linearly from SlowStartMinFactor to 1 over window,
+// counted from born (unix nano). A window <= 0 disables slow start.
+func SlowStartFactor(born int64, window time.Duration, now int64) float64 {
+	if window <= 0 || born <= 0 {
+		return 1
+	}
+	elapsed := now - born
+	if elapsed >= int64(window) {
+		return 1
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return SlowStartMinFactor + (1-SlowStartMinFactor)*float64(elapsed)/float64(window)
+}
@@ -3,6 +3,7 @@ package wrr
 import (
 	"context"
 	"sync"
+	"time"
 
 	"mymicro/micro/server/rpcserver/selector"
 	"mymicro/micro/server/rpcserver/selector/node/direct"
@@ -19,7 +20,16 @@ var _ selector.Balancer = (*Balancer)(nil) // Name is balancer name
 type Option func(o *options)
 
 // options is wrr builder options
-type options struct{}
+type options struct {
+	slowStart time.Duration
+}
+
+// WithSlowStart sets the window over which the weight of a new node ramps up to full.
+func WithSlowStart(d time.Duration) Option {
+	return func(o *options) {
+		o.slowStart = d
+	}
+}
 
 // Balancer is a wrr balancer.
 type Balancer struct {
@@ -69,7 +79,7 @@ func NewBuilder(opts ...Option) selector.Builder {
 	}
 	return &selector.DefaultBuilder{
 		Balancer: &Builder{},
-		Node:     &direct.Builder{},
+		Node:     &direct.Builder{SlowStart: option.slowStart},
 	}
 }
 
@@ -7,13 +7,18 @@ import (
 	"net/url"
 	"time"
 
+	ut "github.com/go-playground/universal-translator"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	apimetadata "mymicro/api/metadata"
+	"mymicro/micro/core/fault"
+	"mymicro/micro/core/load"
+	"mymicro/micro/core/ratelimit"
+	"mymicro/micro/server/rpcserver/accesslog"
+	"mymicro/micro/server/rpcserver/resolver/discovery"
 	srvintc "mymicro/micro/server/rpcserver/serverinterceptors"
 	"mymicro/pkg/host"
 )
@@ -36,6 +41,25 @@ type Server struct {
 	endpoint *url.URL
 
 	enableMetrics bool
+	shedder       load.Shedder
+	rateLimiter   *ratelimit.RuleLimiter
+	faultInjector *fault.Injector
+	// caller identifies the callers of the rate limiter and the fault rules, MetadataCaller if nil
+	caller        srvintc.CallerFunc
+	accessLog     *accesslog.Conf
+	tokenVerifier srvintc.TokenVerifier
+	publicMethods []string
+	tls           *tlsFiles
+
+	// 校验信息的翻译器
+	transName string
+	trans     ut.Translator
+
+	interceptorSwitches map[string]bool
+	unaryInsertions     []unaryInsertion
+	streamInsertions    []streamInsertion
+	unaryChain          []string
+	streamChain         []string
 }
 
 func (s *Server) Address() string { return s.address }
@@ -54,40 +78,46 @@ func (s *Server) listenAndEndpoint() error {
 		_ = s.lis.Close()
 		return err
 	}
-	s.endpoint = &url.URL{
-		Scheme: "grpc",
-		Host:   addr,
-	}
+	s.endpoint = discovery.NewEndpoint("grpc", addr, s.tls != nil)
 	return nil
 }
 
+// Endpoint returns the endpoint registered to the registry, with isSecure=true if TLS is enabled.
+func (s *Server) Endpoint() *url.URL { return s.endpoint }
+
+// TLSEnabled reports whether the server is configured with TLS by WithTLS.
+func (s *Server) TLSEnabled() bool { return s.tls != nil }
+
 func NewServer(opts ...ServerOption) *Server {
 	srv := Server{
-		address: ":0",
-		health:  health.NewServer(),
+		address:   ":0",
+		health:    health.NewServer(),
+		transName: "zh",
 		//timeout: 1 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(&srv)
 	}
-	// 如果用户不设置拦截器，则自动默认加上一些必须的拦截器，例如：recover, timeout, tracing
-	unaryInts := []grpc.UnaryServerInterceptor{
-		srvintc.UnaryRecoverInterceptor,
-		srvintc.UnaryTimeoutInterceptor(srv.timeout),
-		otelgrpc.UnaryServerInterceptor(),
-	}
-
-	if srv.enableMetrics {
-		unaryInts = append(unaryInts, srvintc.UnaryPrometheusInterceptor)
-	}
-	if srv.timeout > 0 {
-		unaryInts = append(unaryInts, srvintc.UnaryTimeoutInterceptor(srv.timeout))
+	// 默认拦截器按固定顺序组装：tracing, logging, metrics, recover, shedding, auth, ratelimit, validate, timeout, fault, errors，用户拦截器可插入其中或追加在最后
+	unaryInts, unaryNames := srv.buildUnaryChain()
+	streamInts, streamNames := srv.buildStreamChain()
+	srv.unaryChain, srv.streamChain = unaryNames, streamNames
+	log.Infof("[gRPC] unary interceptor chain: %s", chainString(unaryNames))
+	log.Infof("[gRPC] stream interceptor chain: %s", chainString(streamNames))
+	// 把拦截器转换成grpc的ServerOption
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInts...),
+		grpc.ChainStreamInterceptor(streamInts...),
 	}
-	if len(srv.unaryInterceptors) > 0 {
-		unaryInts = append(unaryInts, srv.unaryInterceptors...)
+	// TLS证书文件变更后自动重新加载
+	if srv.tls != nil {
+		creds, err := serverTLSCredentials(*srv.tls)
+		if err != nil {
+			log.Errorf("[gRPC] Failed to load tls files: %v", err)
+			return nil
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
 	}
-	// 把用户传入的拦截器转换成grpc的ServerOption
-	grpcOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(srv.unaryInterceptors...)}
 	// 把用户传入的grpc.ServerOption放在一起
 	if len(srv.grpcOpts) > 0 {
 		grpcOpts = append(grpcOpts, srv.grpcOpts...)
@@ -126,6 +156,22 @@ func (s *Server) Stop(_ context.Context) error {
 	return nil
 }
 
+// Attach prepares the server to be served by ServeHTTP on a shared listener instead of Start,
+// e.g. by muxserver, the own listener of the server is closed.
+func (s *Server) Attach(ctx context.Context) {
+	s.baseCtx = ctx
+	_ = s.lis.Close()
+	s.health.Resume()
+}
+
+// Detach stops the server attached to a shared listener,
+// the calls in flight are canceled since grpc can not drain the connections of ServeHTTP gracefully.
+func (s *Server) Detach() {
+	s.health.Shutdown()
+	s.Server.Stop()
+	log.Info("[gRPC] server detached")
+}
+
 func WithAddress(address string) ServerOption {
 	return func(s *Server) {
 		s.address = address
@@ -138,6 +184,84 @@ func WithMetrics(metric bool) ServerOption {
 	}
 }
 
+// WithShedder enables adaptive load shedding, e.g. load.NewAdaptiveShedder().
+func WithShedder(shedder load.Shedder) ServerOption {
+	return func(s *Server) {
+		s.shedder = shedder
+	}
+}
+
+// WithRateLimiter enables the rate limit of each method and caller.
+func WithRateLimiter(limiter *ratelimit.RuleLimiter) ServerOption {
+	return func(s *Server) {
+		s.rateLimiter = limiter
+	}
+}
+
+// WithFaultInjector injects the faults into the methods if the injector is enabled,
+// the rules can be changed by its admin endpoint served on restserver.
+func WithFaultInjector(injector *fault.Injector) ServerOption {
+	return func(s *Server) {
+		s.faultInjector = injector
+	}
+}
+
+// WithTrustCallerMetadata trusts the x-caller metadata of all the peers to identify the callers of
+// the rate limiter and the fault rules, it is only trusted from the peers verified by client certificates
+// by default. Enable it only if the server is reachable by the trusted services alone.
+func WithTrustCallerMetadata(trust bool) ServerOption {
+	return func(s *Server) {
+		s.caller = nil
+		if trust {
+			s.caller = srvintc.TrustedMetadataCaller
+		}
+	}
+}
+
+// WithTransName sets the locale of the validation messages, "zh" by default.
+func WithTransName(transName string) ServerOption {
+	return func(s *Server) {
+		s.transName = transName
+	}
+}
+
+// WithAccessLog enables the access log of each request, e.g. accesslog.DefaultConf().
+func WithAccessLog(conf accesslog.Conf) ServerOption {
+	return func(s *Server) {
+		s.accessLog = &conf
+	}
+}
+
+// WithAuth enables verifying the bearer token of each request, e.g. srvintc.JWTVerifier(signKey),
+// publicMethods are allowed without token, a method ending with "/*" matches all the methods of the service.
+func WithAuth(verifier srvintc.TokenVerifier, publicMethods ...string) ServerOption {
+	return func(s *Server) {
+		s.tokenVerifier = verifier
+		s.publicMethods = publicMethods
+	}
+}
+
+// WithTLS enables TLS with the certificate and key files, which are reloaded on change.
+func WithTLS(certFile, keyFile string) ServerOption {
+	return func(s *Server) {
+		if s.tls == nil {
+			s.tls = &tlsFiles{}
+		}
+		s.tls.certFile, s.tls.keyFile = certFile, keyFile
+	}
+}
+
+// WithClientCA enables mutual TLS, client certificates are required and verified by the CA file.
+// It takes effect with WithTLS.
+func WithClientCA(caFile string) ServerOption {
+	return func(s *Server) {
+		if s.tls == nil {
+			s.tls = &tlsFiles{}
+		}
+		s.tls.caFile = caFile
+	}
+}
+
 func WithLis(lis net.Listener) ServerOption {
 	return func(s *Server) {
 		s.lis = lis
@@ -0,0 +1,223 @@
+package rpcserver
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/load"
+)
+
+const testActionKey = "x-test-action"
+
+// testActionInterceptor panics or sleeps as the test action in incoming metadata says.
+func testActionInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(testActionKey); len(v) > 0 {
+		switch v[0] {
+		case "panic":
+			panic("test panic")
+		case "sleep":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+	return handler(ctx, req)
+}
+
+func codeTotal(method string, code codes.Code) float64 {
+	mfs, _ := prom.DefaultGatherer.Gather()
+	for _, mf := range mfs {
+		if mf.GetName() != "rpc_server_requests_xhy_code_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["method"] == method && labels["code"] == strconv.Itoa(int(code)) {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestUnaryInterceptorChain(t *testing.T) {
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithMetrics(true),
+		WithTimeout(50*time.Millisecond),
+		WithUnaryInterceptor(testActionInterceptor),
+	)
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	defer srv.Stop(context.Background())
+
+	want := []string{InterceptorTracing, InterceptorMetrics, InterceptorRecover, InterceptorValidate,
+		InterceptorTimeout, InterceptorErrors, userInterceptor}
+	if !reflect.DeepEqual(srv.unaryChain, want) {
+		t.Errorf("expect %v, got %v", want, srv.unaryChain)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := DailInsecure(ctx, WithEndpoint(srv.lis.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const method = "/grpc.health.v1.Health/Check"
+	client := grpc_health_v1.NewHealthClient(conn)
+	for _, tt := range []struct {
+		action string
+		code   codes.Code
+	}{
+		{action: "", code: codes.OK},
+		{action: "panic", code: codes.Internal},
+		{action: "sleep", code: codes.DeadlineExceeded},
+	} {
+		before := codeTotal(method, tt.code)
+		_, err = client.Check(metadata.AppendToOutgoingContext(ctx, testActionKey, tt.action),
+			&grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+		if status.Code(err) != tt.code {
+			t.Errorf("action %q: expect %v, got %v", tt.action, tt.code, err)
+		}
+		if after := codeTotal(method, tt.code); after != before+1 {
+			t.Errorf("action %q: expect code total %v, got %v", tt.action, before+1, after)
+		}
+	}
+}
+
+func TestUnaryInterceptorChainOrder(t *testing.T) {
+	noop := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(ctx, req)
+	}
+	streamNoop := func(svr any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(svr, ss)
+	}
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithInterceptorEnabled(InterceptorTracing, false),
+		WithInterceptorEnabled(InterceptorMetrics, true),
+		WithInterceptorEnabled(InterceptorValidate, false),
+		WithInterceptorEnabled(InterceptorErrors, false),
+		WithUnaryInterceptorBefore(InterceptorRecover, noop),
+		WithUnaryInterceptorAfter(InterceptorTracing, noop),
+		WithStreamInterceptorBefore(InterceptorRecover, streamNoop),
+		WithStreamInterceptorAfter(InterceptorTracing, streamNoop),
+	)
+	defer srv.lis.Close()
+
+	want := []string{userInterceptor, InterceptorMetrics, userInterceptor, InterceptorRecover}
+	if !reflect.DeepEqual(srv.unaryChain, want) {
+		t.Errorf("expect %v, got %v", want, srv.unaryChain)
+	}
+	if !reflect.DeepEqual(srv.streamChain, want) {
+		t.Errorf("expect stream chain %v, got %v", want, srv.streamChain)
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	var serverCalls, clientCalls int32
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithMetrics(true),
+		WithTimeout(time.Second),
+		WithStreamInterceptor(func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			atomic.AddInt32(&serverCalls, 1)
+			if _, ok := stream.Context().Deadline(); !ok {
+				t.Errorf("expect stream deadline")
+			}
+			return handler(svr, stream)
+		}),
+	)
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	defer srv.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := DailInsecure(ctx,
+		WithEndpoint(srv.lis.Addr().String()),
+		WithEnableMetrics(true),
+		WithClientStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+			method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			atomic.AddInt32(&clientCalls, 1)
+			return streamer(ctx, desc, cc, method, opts...)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream, err := grpc_health_v1.NewHealthClient(conn).Watch(ctx, &grpc_health_v1.HealthCheckRequest{},
+		grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&serverCalls); n != 1 {
+		t.Errorf("expect 1 server stream interceptor call, got %d", n)
+	}
+	if n := atomic.LoadInt32(&clientCalls); n != 1 {
+		t.Errorf("expect 1 client stream interceptor call, got %d", n)
+	}
+}
+
+// overloadedShedder sheds all requests.
+type overloadedShedder struct{}
+
+func (overloadedShedder) Allow() (load.DoneFunc, error) {
+	return nil, load.ErrServiceOverloaded
+}
+
+func TestShedding(t *testing.T) {
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithShedder(overloadedShedder{}),
+	)
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	defer srv.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := DailInsecure(ctx, WithEndpoint(srv.lis.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := grpc_health_v1.NewHealthClient(conn)
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expect unary call %v, got %v", codes.ResourceExhausted, err)
+	}
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expect stream call %v, got %v", codes.ResourceExhausted, err)
+	}
+}
@@ -0,0 +1,144 @@
+package serverinterceptors
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/server/restserver/middlewares"
+	"mymicro/micro/server/restserver/middlewares/auth"
+)
+
+const (
+	// AuthorizationKey is the metadata key of the bearer token.
+	AuthorizationKey = "authorization"
+	// TokenKey is the metadata key of the raw token, the same as the x-token header of middlewares.JWTAuth.
+	TokenKey = "x-token"
+
+	bearerPrefix = "Bearer "
+)
+
+// defaultPublicMethods are the methods of health checking and reflection, which are always public.
+var defaultPublicMethods = []string{
+	"/grpc.health.v1.Health/*",
+	"/grpc.reflection.v1alpha.ServerReflection/*",
+	"/grpc.reflection.v1.ServerReflection/*",
+}
+
+type (
+	claimsKey struct{}
+
+	// TokenVerifier verifies the token and returns its claims.
+	TokenVerifier func(ctx context.Context, token string) (any, error)
+)
+
+// JWTVerifier returns a TokenVerifier of middlewares.JWT, the claims are *middlewares.CustomClaims.
+func JWTVerifier(signKey string) TokenVerifier {
+	j := middlewares.NewJWT(signKey)
+	return func(_ context.Context, token string) (any, error) {
+		return j.ParseToken(token)
+	}
+}
+
+// CacheVerifier returns a TokenVerifier of auth.CacheStrategy, the claims are auth.Secret.
+func CacheVerifier(strategy auth.CacheStrategy) TokenVerifier {
+	return func(_ context.Context, token string) (any, error) {
+		return strategy.Verify(token)
+	}
+}
+
+// NewClaimsContext returns a new context with the claims of the token.
+func NewClaimsContext(ctx context.Context, claims any) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token if verified.
+func ClaimsFromContext(ctx context.Context) (any, bool) {
+	claims := ctx.Value(claimsKey{})
+	return claims, claims != nil
+}
+
+// UnaryAuthInterceptor returns a func that verifies the bearer token in incoming metadata,
+// and puts its claims in the context. publicMethods are allowed without token,
+// a method ending with "/*" matches all the methods of the service.
+func UnaryAuthInterceptor(verifier TokenVerifier, publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := buildPublicMethods(publicMethods)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if isPublicMethod(public, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor returns a func that verifies the bearer token in incoming metadata,
+// and puts its claims in the context of the stream.
+func StreamAuthInterceptor(verifier TokenVerifier, publicMethods ...string) grpc.StreamServerInterceptor {
+	public := buildPublicMethods(publicMethods)
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if isPublicMethod(public, info.FullMethod) {
+			return handler(svr, stream)
+		}
+		ctx, err := authenticate(stream.Context(), verifier)
+		if err != nil {
+			return err
+		}
+		return handler(svr, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// TokenFromContext returns the bearer token or the raw token in incoming metadata.
+func TokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(AuthorizationKey); len(v) > 0 && strings.HasPrefix(v[0], bearerPrefix) {
+		return strings.TrimPrefix(v[0], bearerPrefix)
+	}
+	if v := md.Get(TokenKey); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func authenticate(ctx context.Context, verifier TokenVerifier) (context.Context, error) {
+	token := TokenFromContext(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+	claims, err := verifier(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return NewClaimsContext(ctx, claims), nil
+}
+
+func buildPublicMethods(methods []string) map[string]struct{} {
+	public := make(map[string]struct{}, len(methods)+len(defaultPublicMethods))
+	for _, m := range append(methods, defaultPublicMethods...) {
+		public[m] = struct{}{}
+	}
+	return public
+}
+
+func isPublicMethod(public map[string]struct{}, method string) bool {
+	if _, ok := public[method]; ok {
+		return true
+	}
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		_, ok := public[method[:i]+"/*"]
+		return ok
+	}
+	return false
+}
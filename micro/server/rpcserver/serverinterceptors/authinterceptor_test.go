@@ -0,0 +1,59 @@
+package serverinterceptors
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/server/restserver/middlewares"
+)
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	const key = "test-key"
+	token, err := middlewares.NewJWT(key).CreateToken(middlewares.CustomClaims{ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryAuthInterceptor(JWTVerifier(key), "/foo.Public/*")
+	handler := func(ctx context.Context, req any) (any, error) {
+		claims, _ := ClaimsFromContext(ctx)
+		return claims, nil
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		code   codes.Code
+	}{
+		{name: "bearer", method: "/foo.Private/Get", md: metadata.Pairs(AuthorizationKey, "Bearer "+token)},
+		{name: "x-token", method: "/foo.Private/Get", md: metadata.Pairs(TokenKey, token)},
+		{name: "missing", method: "/foo.Private/Get", code: codes.Unauthenticated},
+		{name: "invalid", method: "/foo.Private/Get", md: metadata.Pairs(TokenKey, "bad"),
+			code: codes.Unauthenticated},
+		{name: "public", method: "/foo.Public/Get"},
+		{name: "health", method: "/grpc.health.v1.Health/Check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expect %v, got %v", tt.code, err)
+			}
+			if tt.md != nil && tt.code == codes.OK {
+				claims, ok := resp.(*middlewares.CustomClaims)
+				if !ok || claims.ID != 7 {
+					t.Errorf("expect claims of user 7, got %v", resp)
+				}
+				if caller := MetadataCaller(NewClaimsContext(ctx, claims)); caller != "7" {
+					t.Errorf("expect caller 7, got %s", caller)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package serverinterceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"mymicro/micro/server/rpcserver/rpcerrors"
+)
+
+// UnaryErrorInterceptor converts the errors with code of pkg/errors into status errors.
+func UnaryErrorInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	return resp, rpcerrors.ToStatus(err)
+}
+
+// StreamErrorInterceptor converts the errors with code of pkg/errors into status errors.
+func StreamErrorInterceptor(svr any, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	return rpcerrors.ToStatus(handler(svr, stream))
+}
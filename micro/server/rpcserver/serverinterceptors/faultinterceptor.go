@@ -0,0 +1,75 @@
+package serverinterceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/fault"
+	"mymicro/micro/core/metric"
+)
+
+var metricServerFaultTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: serverNamespace,
+	Subsystem: "requests",
+	Name:      "xhy_fault_total",
+	Help:      "rpc server requests fault injected count.",
+	Labels:    []string{"method"},
+})
+
+// UnaryFaultInterceptor returns a func that injects the faults of the injector into unary requests,
+// the rules are keyed by the full method, the caller and the incoming metadata, caller is MetadataCaller if nil.
+func UnaryFaultInterceptor(injector *fault.Injector, caller CallerFunc) grpc.UnaryServerInterceptor {
+	if caller == nil {
+		caller = MetadataCaller
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if err := injectFault(ctx, injector, info.FullMethod, caller); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamFaultInterceptor returns a func that injects the faults of the injector into stream requests,
+// the rules are keyed by the full method, the caller and the incoming metadata, caller is MetadataCaller if nil.
+func StreamFaultInterceptor(injector *fault.Injector, caller CallerFunc) grpc.StreamServerInterceptor {
+	if caller == nil {
+		caller = MetadataCaller
+	}
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if err := injectFault(stream.Context(), injector, info.FullMethod, caller); err != nil {
+			return err
+		}
+		return handler(svr, stream)
+	}
+}
+
+func injectFault(ctx context.Context, injector *fault.Injector, method string, caller CallerFunc) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	rule, ok := injector.Fire(method, caller(ctx), func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	})
+	if !ok {
+		return nil
+	}
+	metricServerFaultTotal.Inc(method)
+	if err := injector.Wait(ctx, rule); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	switch {
+	case rule.Abort:
+		return status.Error(codes.Unavailable, rule.Error())
+	case rule.Code != 0:
+		return status.Error(codes.Code(rule.Code), rule.Error())
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package serverinterceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/fault"
+)
+
+func TestUnaryFaultInterceptor(t *testing.T) {
+	injector, err := fault.NewInjector(fault.Conf{Enabled: true, Rules: []fault.Rule{
+		{Name: "/foo.Foo/Error", Percentage: 100, Code: int(codes.Internal)},
+		{Name: "/foo.Foo/Abort", Percentage: 100, Abort: true},
+		{Name: "/foo.Foo/Slow", Percentage: 100, Delay: time.Hour},
+		{Name: fault.AnyName, Caller: "chaos", Percentage: 100, Code: int(codes.ResourceExhausted)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryFaultInterceptor(injector, TrustedMetadataCaller)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	}
+
+	tests := []struct {
+		method string
+		md     metadata.MD
+		code   codes.Code
+	}{
+		{method: "/foo.Foo/Get"},
+		{method: "/foo.Foo/Error", code: codes.Internal},
+		{method: "/foo.Foo/Abort", code: codes.Unavailable},
+		{method: "/foo.Foo/Slow", code: codes.DeadlineExceeded},
+		{method: "/foo.Foo/Get", md: metadata.Pairs(CallerKey, "chaos"), code: codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		ctx = metadata.NewIncomingContext(ctx, tt.md)
+		_, err := interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+		cancel()
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: expect %v, got %v", tt.method, tt.code, err)
+		}
+	}
+
+	disabled, _ := fault.NewInjector(fault.Conf{})
+	_, err = UnaryFaultInterceptor(disabled, nil)(context.Background(), "req",
+		&grpc.UnaryServerInfo{FullMethod: "/foo.Foo/Error"}, handler)
+	if err != nil {
+		t.Errorf("expect no fault when disabled, got %v", err)
+	}
+}
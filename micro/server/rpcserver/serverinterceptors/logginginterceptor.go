@@ -0,0 +1,64 @@
+package serverinterceptors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/server/rpcserver/accesslog"
+)
+
+const logKind = "server"
+
+// UnaryLoggingInterceptor returns a func that writes the access logs of unary requests.
+func UnaryLoggingInterceptor(conf accesslog.Conf) grpc.UnaryServerInterceptor {
+	logger := accesslog.NewLogger(conf)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		startTime := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Log(ctx, accesslog.Entry{
+			Kind:     logKind,
+			Method:   info.FullMethod,
+			Peer:     peerAddr(ctx),
+			Caller:   callerFromMetadata(ctx),
+			Code:     status.Code(err),
+			Err:      err,
+			Latency:  time.Since(startTime),
+			Request:  req,
+			Response: resp,
+		})
+		return resp, err
+	}
+}
+
+// StreamLoggingInterceptor returns a func that writes the access logs of stream requests.
+func StreamLoggingInterceptor(conf accesslog.Conf) grpc.StreamServerInterceptor {
+	logger := accesslog.NewLogger(conf)
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		startTime := time.Now()
+		err := handler(svr, stream)
+		ctx := stream.Context()
+		logger.Log(ctx, accesslog.Entry{
+			Kind:    logKind,
+			Method:  info.FullMethod,
+			Peer:    peerAddr(ctx),
+			Caller:  callerFromMetadata(ctx),
+			Code:    status.Code(err),
+			Err:     err,
+			Latency: time.Since(startTime),
+		})
+		return err
+	}
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
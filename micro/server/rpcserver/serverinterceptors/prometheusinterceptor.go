@@ -28,8 +28,43 @@ var (
 		Help:      "rpc server requests code count.",
 		Labels:    []string{"method", "code"},
 	})
+
+	metricServerStreamMsgTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: serverNamespace,
+		Subsystem: "requests",
+		Name:      "xhy_stream_msg_total",
+		Help:      "rpc server stream messages count.",
+		Labels:    []string{"method", "direction"},
+	})
 )
 
+const (
+	directionSent     = "sent"
+	directionReceived = "received"
+)
+
+// monitoredServerStream counts the messages of a server stream.
+type monitoredServerStream struct {
+	grpc.ServerStream
+	method string
+}
+
+func (s *monitoredServerStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		metricServerStreamMsgTotal.Inc(s.method, directionSent)
+	}
+	return err
+}
+
+func (s *monitoredServerStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		metricServerStreamMsgTotal.Inc(s.method, directionReceived)
+	}
+	return err
+}
+
 func UnaryPrometheusInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler) (resp interface{}, err error) {
 	startTime := time.Now()
@@ -40,3 +75,13 @@ func UnaryPrometheusInterceptor(ctx context.Context, req interface{}, info *grpc
 	metricServerReqCodeTotal.Inc(info.FullMethod, strconv.Itoa(int(status.Code(err))))
 	return resp, err
 }
+
+// StreamPrometheusInterceptor records the duration, code and message counts of stream requests.
+func StreamPrometheusInterceptor(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	startTime := time.Now()
+	err := handler(svr, &monitoredServerStream{ServerStream: stream, method: info.FullMethod})
+	metricServerReqDur.Observe(int64(time.Since(startTime)/time.Millisecond), info.FullMethod)
+	metricServerReqCodeTotal.Inc(info.FullMethod, strconv.Itoa(int(status.Code(err))))
+	return err
+}
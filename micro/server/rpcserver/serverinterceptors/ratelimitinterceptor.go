@@ -0,0 +1,107 @@
+package serverinterceptors
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/ratelimit"
+	"mymicro/micro/server/restserver/middlewares"
+	"mymicro/micro/server/restserver/middlewares/auth"
+)
+
+// CallerKey is the metadata key of the calling service or user.
+const CallerKey = "x-caller"
+
+// CallerFunc returns the identity of the caller of the request.
+type CallerFunc func(ctx context.Context) string
+
+// MetadataCaller returns the user of the verified token, the CallerKey in incoming metadata,
+// or the peer ip in order. The CallerKey is only trusted from the peers verified by client certificates,
+// since the other peers can send any value, use TrustedMetadataCaller to trust it from all the peers.
+func MetadataCaller(ctx context.Context) string {
+	return metadataCaller(ctx, false)
+}
+
+// TrustedMetadataCaller is MetadataCaller trusting the CallerKey of all the peers,
+// e.g. when the server is only reachable by the trusted services.
+func TrustedMetadataCaller(ctx context.Context) string {
+	return metadataCaller(ctx, true)
+}
+
+func metadataCaller(ctx context.Context, trustAll bool) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		switch c := claims.(type) {
+		case *middlewares.CustomClaims:
+			return strconv.FormatUint(uint64(c.ID), 10)
+		case auth.Secret:
+			return c.Username
+		}
+	}
+	p, hasPeer := peer.FromContext(ctx)
+	if trustAll || (hasPeer && verifiedPeer(p)) {
+		if caller := callerFromMetadata(ctx); caller != "" {
+			return caller
+		}
+	}
+	if hasPeer && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// verifiedPeer reports whether the peer is authenticated by a verified client certificate.
+func verifiedPeer(p *peer.Peer) bool {
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) > 0
+}
+
+func callerFromMetadata(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(CallerKey); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// UnaryRateLimitInterceptor returns a func that rejects unary requests over quota with codes.ResourceExhausted,
+// the rules are keyed by the full method and the caller, caller is MetadataCaller if nil.
+func UnaryRateLimitInterceptor(limiter *ratelimit.RuleLimiter, caller CallerFunc) grpc.UnaryServerInterceptor {
+	if caller == nil {
+		caller = MetadataCaller
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if err := limiter.Allow(ctx, info.FullMethod, caller(ctx)); err != nil {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimitInterceptor returns a func that rejects stream requests over quota with codes.ResourceExhausted,
+// the rules are keyed by the full method and the caller, caller is MetadataCaller if nil.
+func StreamRateLimitInterceptor(limiter *ratelimit.RuleLimiter, caller CallerFunc) grpc.StreamServerInterceptor {
+	if caller == nil {
+		caller = MetadataCaller
+	}
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		if err := limiter.Allow(ctx, info.FullMethod, caller(ctx)); err != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return handler(svr, stream)
+	}
+}
@@ -0,0 +1,41 @@
+package serverinterceptors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestMetadataCaller(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}
+	verified := credentials.TLSInfo{State: tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{&x509.Certificate{}}},
+	}}
+
+	tests := []struct {
+		name   string
+		auth   credentials.AuthInfo
+		caller CallerFunc
+		want   string
+	}{
+		{name: "untrusted", caller: MetadataCaller, want: "10.0.0.1"},
+		{name: "tls without client cert", auth: credentials.TLSInfo{}, caller: MetadataCaller, want: "10.0.0.1"},
+		{name: "client cert", auth: verified, caller: MetadataCaller, want: "order"},
+		{name: "trusted", caller: TrustedMetadataCaller, want: "order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: tt.auth})
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(CallerKey, "order"))
+			if got := tt.caller(ctx); got != tt.want {
+				t.Errorf("expect %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
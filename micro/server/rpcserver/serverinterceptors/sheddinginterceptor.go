@@ -0,0 +1,52 @@
+package serverinterceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/load"
+	"mymicro/micro/core/metric"
+)
+
+var metricServerShedTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: serverNamespace,
+	Subsystem: "requests",
+	Name:      "xhy_shed_total",
+	Help:      "rpc server requests shed count.",
+	Labels:    []string{"method"},
+})
+
+// UnarySheddingInterceptor returns a func that rejects unary requests with codes.ResourceExhausted
+// when the shedder reports the server is overloaded.
+func UnarySheddingInterceptor(shedder load.Shedder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		done, err := shedder.Allow()
+		if err != nil {
+			metricServerShedTotal.Inc(info.FullMethod)
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		defer done()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamSheddingInterceptor returns a func that rejects stream requests with codes.ResourceExhausted
+// when the shedder reports the server is overloaded.
+func StreamSheddingInterceptor(shedder load.Shedder) grpc.StreamServerInterceptor {
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		done, err := shedder.Allow()
+		if err != nil {
+			metricServerShedTotal.Inc(info.FullMethod)
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		defer done()
+
+		return handler(svr, stream)
+	}
+}
@@ -0,0 +1,95 @@
+package serverinterceptors
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/load"
+)
+
+// testShedder sheds all requests if overloaded, and counts the done calls of the allowed ones.
+type testShedder struct {
+	overloaded bool
+	done       int
+}
+
+func (s *testShedder) Allow() (load.DoneFunc, error) {
+	if s.overloaded {
+		return nil, load.ErrServiceOverloaded
+	}
+	return func() { s.done++ }, nil
+}
+
+func TestUnarySheddingInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		overloaded bool
+		code       codes.Code
+	}{
+		{name: "allowed", code: codes.OK},
+		{name: "overloaded", overloaded: true, code: codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shedder := &testShedder{overloaded: tt.overloaded}
+			var called bool
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				if shedder.done != 0 {
+					t.Errorf("expect done after the handler")
+				}
+				return nil, nil
+			}
+			_, err := UnarySheddingInterceptor(shedder)(context.Background(), nil,
+				&grpc.UnaryServerInfo{FullMethod: "/test.Service/Shedding"}, handler)
+			if status.Code(err) != tt.code {
+				t.Errorf("expect %v, got %v", tt.code, err)
+			}
+			if called == tt.overloaded {
+				t.Errorf("expect handler called %v, got %v", !tt.overloaded, called)
+			}
+			if !tt.overloaded && shedder.done != 1 {
+				t.Errorf("expect done once, got %d", shedder.done)
+			}
+		})
+	}
+}
+
+func TestStreamSheddingInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		overloaded bool
+		code       codes.Code
+	}{
+		{name: "allowed", code: codes.OK},
+		{name: "overloaded", overloaded: true, code: codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shedder := &testShedder{overloaded: tt.overloaded}
+			var called bool
+			handler := func(svr any, stream grpc.ServerStream) error {
+				called = true
+				if shedder.done != 0 {
+					t.Errorf("expect done after the handler")
+				}
+				return nil
+			}
+			err := StreamSheddingInterceptor(shedder)(nil, nil,
+				&grpc.StreamServerInfo{FullMethod: "/test.Service/Shedding"}, handler)
+			if status.Code(err) != tt.code {
+				t.Errorf("expect %v, got %v", tt.code, err)
+			}
+			if called == tt.overloaded {
+				t.Errorf("expect handler called %v, got %v", !tt.overloaded, called)
+			}
+			if !tt.overloaded && shedder.done != 1 {
+				t.Errorf("expect done once, got %d", shedder.done)
+			}
+		})
+	}
+}
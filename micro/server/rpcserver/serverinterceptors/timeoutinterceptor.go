@@ -22,6 +22,12 @@ type (
 	}
 
 	methodTimeouts map[string]time.Duration
+
+	// wrappedServerStream overrides the context of a server stream.
+	wrappedServerStream struct {
+		grpc.ServerStream
+		ctx context.Context
+	}
 )
 
 // UnaryTimeoutInterceptor returns a func that sets timeout to incoming unary requests.
@@ -73,6 +79,28 @@ func UnaryTimeoutInterceptor(timeout time.Duration,
 	}
 }
 
+// StreamTimeoutInterceptor returns a func that sets timeout to incoming stream requests,
+// the timeout applies to the whole stream.
+func StreamTimeoutInterceptor(timeout time.Duration,
+	methodTimeouts ...MethodTimeoutConf) grpc.StreamServerInterceptor {
+	timeouts := buildMethodTimeouts(methodTimeouts)
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		t := getTimeoutByUnaryServerInfo(info.FullMethod, timeouts, timeout)
+		if t <= 0 {
+			return handler(svr, stream)
+		}
+		ctx, cancel := context.WithTimeout(stream.Context(), t)
+		defer cancel()
+
+		return handler(svr, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
+
 func buildMethodTimeouts(timeouts []MethodTimeoutConf) methodTimeouts {
 	mt := make(methodTimeouts, len(timeouts))
 	for _, st := range timeouts {
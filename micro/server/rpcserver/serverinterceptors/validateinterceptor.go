@@ -0,0 +1,123 @@
+package serverinterceptors
+
+import (
+	"context"
+
+	ut "github.com/go-playground/universal-translator"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/server/restserver/validation"
+)
+
+type (
+	// allValidator is implemented by messages generated by protoc-gen-validate, it returns all the violations.
+	allValidator interface {
+		ValidateAll() error
+	}
+
+	// validator is implemented by messages generated by protoc-gen-validate, it returns the first violation.
+	validator interface {
+		Validate() error
+	}
+
+	// fieldError is implemented by the validation errors generated by protoc-gen-validate.
+	fieldError interface {
+		Field() string
+		Reason() string
+		Cause() error
+	}
+
+	// multiError is implemented by the errors of ValidateAll.
+	multiError interface {
+		AllErrors() []error
+	}
+)
+
+// UnaryValidateInterceptor returns a func that validates the requests generated by protoc-gen-validate,
+// the violations are returned as codes.InvalidArgument with BadRequest details translated by trans.
+func UnaryValidateInterceptor(trans ut.Translator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if err := validateMessage(req, trans); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidateInterceptor returns a func that validates the received messages generated by protoc-gen-validate.
+func StreamValidateInterceptor(trans ut.Translator) grpc.StreamServerInterceptor {
+	return func(svr any, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		return handler(svr, &validateServerStream{ServerStream: stream, trans: trans})
+	}
+}
+
+type validateServerStream struct {
+	grpc.ServerStream
+	trans ut.Translator
+}
+
+func (s *validateServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validateMessage(m, s.trans)
+}
+
+func validateMessage(m any, trans ut.Translator) error {
+	var err error
+	switch v := m.(type) {
+	case allValidator:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	default:
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	collectViolations(err, "", trans, &violations)
+	if len(violations) == 0 {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	st, derr := status.New(codes.InvalidArgument, violations[0].Description).
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if derr != nil {
+		return status.Error(codes.InvalidArgument, violations[0].Description)
+	}
+	return st.Err()
+}
+
+// collectViolations flattens the errors of embedded messages, the fields are joined by dots.
+func collectViolations(err error, prefix string, trans ut.Translator,
+	violations *[]*errdetails.BadRequest_FieldViolation) {
+	if me, ok := err.(multiError); ok {
+		for _, e := range me.AllErrors() {
+			collectViolations(e, prefix, trans, violations)
+		}
+		return
+	}
+	fe, ok := err.(fieldError)
+	if !ok {
+		return
+	}
+	field := prefix + fe.Field()
+	if cause := fe.Cause(); cause != nil {
+		before := len(*violations)
+		collectViolations(cause, field+".", trans, violations)
+		if len(*violations) > before {
+			return
+		}
+	}
+	*violations = append(*violations, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: validation.TranslateProtoReason(trans, field, fe.Reason()),
+	})
+}
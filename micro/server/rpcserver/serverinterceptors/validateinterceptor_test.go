@@ -0,0 +1,86 @@
+package serverinterceptors
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/server/restserver/validation"
+)
+
+type (
+	testFieldError struct {
+		field  string
+		reason string
+		cause  error
+	}
+
+	testMultiError []error
+
+	testRequest struct {
+		err error
+	}
+)
+
+func (e testFieldError) Field() string  { return e.field }
+func (e testFieldError) Reason() string { return e.reason }
+func (e testFieldError) Cause() error   { return e.cause }
+func (e testFieldError) Error() string  { return e.field + ": " + e.reason }
+
+func (m testMultiError) AllErrors() []error { return m }
+func (m testMultiError) Error() string      { return "multiple errors" }
+
+func (r *testRequest) ValidateAll() error { return r.err }
+
+func TestUnaryValidateInterceptor(t *testing.T) {
+	trans, err := validation.NewTranslator("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &testRequest{err: testMultiError{
+		testFieldError{field: "name", reason: "value length must be at least 3 runes"},
+		testFieldError{field: "address", reason: "embedded message failed validation",
+			cause: testFieldError{field: "city", reason: "value is required"}},
+	}}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	}
+
+	_, err = UnaryValidateInterceptor(trans)(context.Background(), req, &grpc.UnaryServerInfo{}, handler)
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expect %v, got %v", codes.InvalidArgument, err)
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.GetFieldViolations()
+		}
+	}
+	want := map[string]string{
+		"name":         "name must be at least 3 characters in length",
+		"address.city": "address.city is a required field",
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("expect %d violations, got %v", len(want), violations)
+	}
+	for _, v := range violations {
+		if want[v.GetField()] != v.GetDescription() {
+			t.Errorf("expect %q, got %q", want[v.GetField()], v.GetDescription())
+		}
+	}
+
+	req.err = nil
+	if _, err = UnaryValidateInterceptor(trans)(context.Background(), req, &grpc.UnaryServerInfo{},
+		handler); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+	if !strings.Contains(validation.TranslateProtoReason(nil, "x", "value is required"), "required") {
+		t.Errorf("expect the reason without translator")
+	}
+}
@@ -0,0 +1,258 @@
+package rpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+
+	"mymicro/pkg/log"
+)
+
+// tlsReloadInterval is the min interval of checking the certificate files for changes.
+var tlsReloadInterval = 10 * time.Second
+
+// fileReloader reloads the files by load when their modification time changes,
+// the last loaded content is kept if reloading fails.
+type fileReloader struct {
+	files []string
+	load  func() error
+
+	mu       sync.Mutex
+	checked  time.Time
+	modTimes []time.Time
+}
+
+func newFileReloader(load func() error, files ...string) (*fileReloader, error) {
+	r := &fileReloader{files: files, load: load}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err = load(); err != nil {
+		return nil, err
+	}
+	r.modTimes = modTimes
+	r.checked = time.Now()
+	return r, nil
+}
+
+func (r *fileReloader) stat() ([]time.Time, error) {
+	modTimes := make([]time.Time, len(r.files))
+	for i, f := range r.files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (r *fileReloader) maybeReload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if now.Sub(r.checked) < tlsReloadInterval {
+		return
+	}
+	r.checked = now
+	modTimes, err := r.stat()
+	if err != nil {
+		log.Errorf("[gRPC] Failed to stat tls files %v: %v", r.files, err)
+		return
+	}
+	changed := false
+	for i := range modTimes {
+		if !modTimes[i].Equal(r.modTimes[i]) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err = r.load(); err != nil {
+		log.Errorf("[gRPC] Failed to reload tls files %v: %v", r.files, err)
+		return
+	}
+	r.modTimes = modTimes
+	log.Infof("[gRPC] tls files %v reloaded", r.files)
+}
+
+// keyPair is the certificate and key reloaded from disk.
+type keyPair struct {
+	*fileReloader
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newKeyPair(certFile, keyFile string) (*keyPair, error) {
+	kp := &keyPair{}
+	r, err := newFileReloader(func() error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		kp.mu.Lock()
+		kp.cert = &cert
+		kp.mu.Unlock()
+		return nil
+	}, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	kp.fileReloader = r
+	return kp, nil
+}
+
+func (kp *keyPair) get() *tls.Certificate {
+	kp.maybeReload()
+	kp.mu.RLock()
+	defer kp.mu.RUnlock()
+	return kp.cert
+}
+
+// certPool is the CA certificates reloaded from disk.
+type certPool struct {
+	*fileReloader
+	mu   sync.RWMutex
+	pool *x509.CertPool
+}
+
+func newCertPool(caFile string) (*certPool, error) {
+	cp := &certPool{}
+	r, err := newFileReloader(func() error {
+		b, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no certificate found in %s", caFile)
+		}
+		cp.mu.Lock()
+		cp.pool = pool
+		cp.mu.Unlock()
+		return nil
+	}, caFile)
+	if err != nil {
+		return nil, err
+	}
+	cp.fileReloader = r
+	return cp, nil
+}
+
+func (cp *certPool) get() *x509.CertPool {
+	cp.maybeReload()
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	return cp.pool
+}
+
+// tlsFiles is the certificate files of the server or client.
+type tlsFiles struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	serverName string
+}
+
+// serverTLSCredentials returns the server credentials of the certificate,
+// client certificates are required and verified by caFile if it is set.
+func serverTLSCredentials(files tlsFiles) (credentials.TransportCredentials, error) {
+	kp, err := newKeyPair(files.certFile, files.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var cas *certPool
+	if files.caFile != "" {
+		if cas, err = newCertPool(files.caFile); err != nil {
+			return nil, err
+		}
+	}
+	return &reloadingCredentials{build: func() *tls.Config {
+		cfg := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{*kp.get()},
+		}
+		if cas != nil {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			cfg.ClientCAs = cas.get()
+		}
+		return cfg
+	}}, nil
+}
+
+// clientTLSCredentials returns the client credentials verifying the server by caFile,
+// or the system roots if caFile is empty, the client certificate is sent if it is set.
+func clientTLSCredentials(files tlsFiles) (credentials.TransportCredentials, error) {
+	var (
+		kp  *keyPair
+		cas *certPool
+		err error
+	)
+	if files.certFile != "" {
+		if kp, err = newKeyPair(files.certFile, files.keyFile); err != nil {
+			return nil, err
+		}
+	}
+	if files.caFile != "" {
+		if cas, err = newCertPool(files.caFile); err != nil {
+			return nil, err
+		}
+	}
+	return &reloadingCredentials{serverName: files.serverName, build: func() *tls.Config {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if kp != nil {
+			cfg.Certificates = []tls.Certificate{*kp.get()}
+		}
+		if cas != nil {
+			cfg.RootCAs = cas.get()
+		}
+		return cfg
+	}}, nil
+}
+
+// reloadingCredentials builds the tls config of each handshake, so that the reloaded files take effect
+// on new connections.
+type reloadingCredentials struct {
+	serverName string
+	build      func() *tls.Config
+}
+
+var _ credentials.TransportCredentials = (*reloadingCredentials)(nil)
+
+func (c *reloadingCredentials) creds() credentials.TransportCredentials {
+	cfg := c.build()
+	cfg.ServerName = c.serverName
+	return credentials.NewTLS(cfg)
+}
+
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string,
+	conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.creds().ClientHandshake(ctx, authority, conn)
+}
+
+func (c *reloadingCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.creds().ServerHandshake(conn)
+}
+
+func (c *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls", SecurityVersion: "1.2", ServerName: c.serverName}
+}
+
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{serverName: c.serverName, build: c.build}
+}
+
+func (c *reloadingCredentials) OverrideServerName(serverName string) error {
+	c.serverName = serverName
+	return nil
+}
@@ -0,0 +1,176 @@
+package rpcserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+
+	"mymicro/micro/server/rpcserver/resolver/discovery"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) writeCA(t *testing.T, file string) {
+	writePEM(t, file, "CERTIFICATE", ca.cert.Raw)
+}
+
+// writeKeyPair issues a certificate of the serial for 127.0.0.1 and writes it with its key.
+func (ca *testCA) writeKeyPair(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	old := tlsReloadInterval
+	tlsReloadInterval = 0
+	defer func() { tlsReloadInterval = old }()
+
+	dir := t.TempDir()
+	file := func(name string) string { return filepath.Join(dir, name) }
+	ca := newTestCA(t)
+	ca.writeCA(t, file("ca.pem"))
+	ca.writeKeyPair(t, file("server.pem"), file("server.key"), 2)
+	ca.writeKeyPair(t, file("client.pem"), file("client.key"), 3)
+
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithTLS(file("server.pem"), file("server.key")),
+		WithClientCA(file("ca.pem")),
+	)
+	if srv == nil {
+		t.Fatal("expect server, got nil")
+	}
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	defer srv.Stop(context.Background())
+
+	if !discovery.IsSecure(srv.Endpoint()) {
+		t.Errorf("expect secure endpoint, got %s", srv.Endpoint())
+	}
+	if !srv.TLSEnabled() {
+		t.Errorf("expect TLS enabled")
+	}
+
+	// serverSerial checks the call and returns the serial of the server certificate.
+	serverSerial := func(opts ...ClientOption) (int64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		opts = append([]ClientOption{WithEndpoint(srv.lis.Addr().String()), WithRootCA(file("ca.pem"))}, opts...)
+		conn, err := Dail(ctx, opts...)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		var p peer.Peer
+		_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Peer(&p))
+		if err != nil {
+			return 0, err
+		}
+		return peerSerial(p), nil
+	}
+
+	serial, err := serverSerial(WithClientCert(file("client.pem"), file("client.key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial != 2 {
+		t.Errorf("expect serial 2, got %d", serial)
+	}
+
+	if _, err = serverSerial(); err == nil {
+		t.Error("expect error without client certificate, got nil")
+	}
+
+	// 证书文件更新后，新连接使用新证书
+	ca.writeKeyPair(t, file("server.pem"), file("server.key"), 4)
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{file("server.pem"), file("server.key")} {
+		if err = os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serial, err = serverSerial(WithClientCert(file("client.pem"), file("client.key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial != 4 {
+		t.Errorf("expect serial 4 after reload, got %d", serial)
+	}
+}
+
+func peerSerial(p peer.Peer) int64 {
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return 0
+	}
+	return info.State.PeerCertificates[0].SerialNumber.Int64()
+}
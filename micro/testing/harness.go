@@ -0,0 +1,210 @@
+// Package testing runs the services built on mymicro in-process for tests.
+//
+// The rpc servers listen on in-memory bufconn listeners and are registered to an in-memory registry,
+// the clients dial them by discovery through the full interceptor and selector stack.
+// Faults injects errors and delays into the server calls by the rules of fault.Injector, and the delays
+// and the retry backoff (clientinterceptors.WithRetryClock) can be driven by the fake Clock.
+package testing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/test/bufconn"
+
+	"mymicro/micro/core/fault"
+	"mymicro/micro/registry"
+	"mymicro/micro/server/restserver"
+	"mymicro/micro/server/rpcserver"
+)
+
+const bufSize = 1 << 20
+
+// TB is the subset of testing.TB used by the harness.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+}
+
+// Harness starts the servers in-process and dials them, everything is cleaned up with the test.
+type Harness struct {
+	Registry *Registry
+	Clock    *Clock
+	// Faults injects the faults into the calls of the rpc servers, its delays are waited by Clock.
+	Faults *fault.Injector
+
+	t TB
+
+	mu        sync.Mutex
+	port      int
+	listeners map[string]*bufconn.Listener
+	calls     map[string]int
+}
+
+// bufListener is a bufconn listener with a fake tcp address, so that the endpoint can be extracted
+// and registered like a real one.
+type bufListener struct {
+	*bufconn.Listener
+	addr *net.TCPAddr
+}
+
+func (l *bufListener) Addr() net.Addr { return l.addr }
+
+// New creates a harness, the fake clock starts at the current time.
+func New(t TB) *Harness {
+	clk := NewClock(time.Now())
+	faults, err := fault.NewInjector(fault.Conf{Enabled: true}, fault.WithClock(clk))
+	if err != nil {
+		t.Fatalf("failed to create fault injector: %v", err)
+		return nil
+	}
+	return &Harness{
+		Registry:  NewRegistry(),
+		Clock:     clk,
+		Faults:    faults,
+		t:         t,
+		port:      10000,
+		listeners: make(map[string]*bufconn.Listener),
+		calls:     make(map[string]int),
+	}
+}
+
+// Calls returns the number of unary calls of the full method received by the rpc servers,
+// including the calls failed by the faults.
+func (h *Harness) Calls(fullMethod string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[fullMethod]
+}
+
+// ResetCalls resets the call counts.
+func (h *Harness) ResetCalls() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = make(map[string]int)
+}
+
+// countCalls counts the unary calls before the faults are injected.
+func (h *Harness) countCalls(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	h.mu.Lock()
+	h.calls[info.FullMethod]++
+	h.mu.Unlock()
+	return handler(ctx, req)
+}
+
+func (h *Harness) listen() *bufListener {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.port++
+	lis := &bufListener{
+		Listener: bufconn.Listen(bufSize),
+		addr:     &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: h.port},
+	}
+	h.listeners[lis.addr.String()] = lis.Listener
+	return lis
+}
+
+func (h *Harness) dial(ctx context.Context, addr string) (net.Conn, error) {
+	h.mu.Lock()
+	lis, ok := h.listeners[addr]
+	h.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no in-process server listening on %s", addr)
+	}
+	return lis.DialContext(ctx)
+}
+
+// StartRPC starts an rpcserver of the service on a bufconn listener and registers it to the registry,
+// register registers the implementations on the server before it starts.
+// The faults are injected by the fault interceptor of the server, the calls are counted before it.
+// StartRPC can be called several times for the instances of a service.
+func (h *Harness) StartRPC(name string, register func(srv *rpcserver.Server),
+	opts ...rpcserver.ServerOption) *rpcserver.Server {
+	h.t.Helper()
+	lis := h.listen()
+	opts = append([]rpcserver.ServerOption{
+		rpcserver.WithLis(lis),
+		rpcserver.WithAddress(lis.addr.String()),
+		rpcserver.WithFaultInjector(h.Faults),
+		rpcserver.WithUnaryInterceptorBefore(rpcserver.InterceptorFault, h.countCalls),
+	}, opts...)
+	srv := rpcserver.NewServer(opts...)
+	if srv == nil {
+		h.t.Fatalf("failed to create rpc server of %s", name)
+		return nil
+	}
+	if register != nil {
+		register(srv)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = srv.Start(ctx)
+	}()
+
+	instance := &registry.ServiceInstance{
+		ID:        fmt.Sprintf("%s-%d", name, lis.addr.Port),
+		Name:      name,
+		Endpoints: []string{srv.Endpoint().String()},
+	}
+	_ = h.Registry.Register(ctx, instance)
+	h.t.Cleanup(func() {
+		_ = h.Registry.Deregister(context.Background(), instance)
+		_ = srv.Stop(context.Background())
+		cancel()
+		<-done
+	})
+	return srv
+}
+
+// Dial returns the client of the service dialed by discovery, it is connected when returned.
+func (h *Harness) Dial(name string, opts ...rpcserver.ClientOption) *grpc.ClientConn {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opts = append([]rpcserver.ClientOption{
+		rpcserver.WithEndpoint("discovery:///" + name),
+		rpcserver.WithDiscovery(h.Registry),
+		rpcserver.WithClientOptions(grpc.WithContextDialer(h.dial)),
+	}, opts...)
+	conn, err := rpcserver.DailInsecure(ctx, opts...)
+	if err != nil {
+		h.t.Fatalf("failed to dial %s: %v", name, err)
+		return nil
+	}
+	h.t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			h.t.Fatalf("failed to connect %s: %v", name, state)
+			return nil
+		}
+	}
+	return conn
+}
+
+// StartRest serves the restserver by an httptest server.
+func (h *Harness) StartRest(srv *restserver.Server) *httptest.Server {
+	h.t.Helper()
+	handler, err := srv.Handler()
+	if err != nil {
+		h.t.Fatalf("failed to init rest server: %v", err)
+		return nil
+	}
+	ts := httptest.NewServer(handler)
+	h.t.Cleanup(ts.Close)
+	return ts
+}
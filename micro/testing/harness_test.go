@@ -0,0 +1,99 @@
+package testing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"mymicro/micro/core/fault"
+	"mymicro/micro/server/restserver"
+	"mymicro/micro/server/rpcserver"
+	"mymicro/micro/server/rpcserver/clientinterceptors"
+	mtesting "mymicro/micro/testing"
+)
+
+const checkMethod = "/grpc.health.v1.Health/Check"
+
+func TestHarness(t *testing.T) {
+	h := mtesting.New(t)
+	h.StartRPC("user", nil)
+	h.StartRPC("user", nil)
+
+	conf := clientinterceptors.RetryConf{
+		MaxAttempts:    3,
+		Codes:          []codes.Code{codes.Unavailable},
+		InitialBackoff: 100 * time.Millisecond,
+		Multiplier:     2,
+	}
+	conn := h.Dial("user", rpcserver.WithClientTimeout(5*time.Second),
+		rpcserver.WithRetry(conf, clientinterceptors.WithRetryClock(h.Clock)))
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := h.Calls(checkMethod); calls != 1 {
+		t.Errorf("expect 1 call, got %d", calls)
+	}
+
+	// 前两次调用失败，由假时钟推进重试的退避时间
+	h.ResetCalls()
+	if err := h.Faults.SetRules(fault.Rule{Name: checkMethod, Percentage: 100, Code: int(codes.Unavailable),
+		Times: 2}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		done <- err
+	}()
+	for i := 0; i < 2; i++ {
+		if !h.Clock.BlockUntil(1, time.Second) {
+			t.Fatal("expect retry backoff waiting on the clock")
+		}
+		h.Clock.Advance(time.Minute)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if calls := h.Calls(checkMethod); calls != 3 {
+		t.Errorf("expect 3 calls, got %d", calls)
+	}
+
+	// 假时钟不推进，延迟一直持续到客户端超时
+	if err := h.Faults.SetRules(fault.Rule{Name: fault.AnyName, Percentage: 100, Delay: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expect %v, got %v", codes.DeadlineExceeded, err)
+	}
+}
+
+func TestHarnessRest(t *testing.T) {
+	h := mtesting.New(t)
+	srv := restserver.NewServer(restserver.WithMode(gin.TestMode), restserver.WithEnableProfiling(false))
+	srv.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	ts := h.StartRest(srv)
+
+	res, err := http.Get(ts.URL + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Errorf("expect 200 pong, got %d %s", res.StatusCode, body)
+	}
+}
@@ -0,0 +1,131 @@
+package testing
+
+import (
+	"context"
+	"sync"
+
+	"mymicro/micro/registry"
+)
+
+// Registry is an in-memory registry.Registrar and registry.Discovery.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string][]*registry.ServiceInstance
+	watchers map[string]map[*watcher]struct{}
+}
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// NewRegistry creates an empty in-memory registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		services: make(map[string][]*registry.ServiceInstance),
+		watchers: make(map[string]map[*watcher]struct{}),
+	}
+}
+
+// Register registers the instance, the instance of the same ID is replaced.
+func (r *Registry) Register(_ context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ins := r.services[service.Name]
+	for i, in := range ins {
+		if in.ID == service.ID {
+			ins[i] = service
+			r.notify(service.Name)
+			return nil
+		}
+	}
+	r.services[service.Name] = append(ins, service)
+	r.notify(service.Name)
+	return nil
+}
+
+// Deregister removes the instance of the same ID.
+func (r *Registry) Deregister(_ context.Context, service *registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ins := r.services[service.Name]
+	for i, in := range ins {
+		if in.ID == service.ID {
+			r.services[service.Name] = append(ins[:i:i], ins[i+1:]...)
+			r.notify(service.Name)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *Registry) GetService(_ context.Context, serviceName string) ([]*registry.ServiceInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*registry.ServiceInstance(nil), r.services[serviceName]...), nil
+}
+
+func (r *Registry) Watch(ctx context.Context, serviceName string) (registry.Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watcher{
+		r:       r,
+		name:    serviceName,
+		ctx:     ctx,
+		cancel:  cancel,
+		changed: make(chan struct{}, 1),
+		first:   true,
+	}
+	w.changed <- struct{}{}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.watchers[serviceName] == nil {
+		r.watchers[serviceName] = make(map[*watcher]struct{})
+	}
+	r.watchers[serviceName][w] = struct{}{}
+	return w, nil
+}
+
+// notify wakes up the watchers of the service, r.mu must be held.
+func (r *Registry) notify(serviceName string) {
+	for w := range r.watchers[serviceName] {
+		select {
+		case w.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+type watcher struct {
+	r       *Registry
+	name    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	changed chan struct{}
+	first   bool
+}
+
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case <-w.changed:
+		}
+		ins, _ := w.r.GetService(w.ctx, w.name)
+		// 第一次监听时服务实例为空则继续等待
+		if w.first && len(ins) == 0 {
+			w.first = false
+			continue
+		}
+		w.first = false
+		return ins, nil
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+	delete(w.r.watchers[w.name], w)
+	return nil
+}
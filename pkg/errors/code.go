@@ -66,6 +66,12 @@ func (coder defaultCoder) Reference() string {
 	return coder.Ref
 }
 
+// NewCoder returns a Coder which is not registered,
+// e.g. to rebuild the coder of a remote error with WrapCoder.
+func NewCoder(code int, httpStatus int, ext string, ref string) Coder {
+	return defaultCoder{C: code, HTTP: httpStatus, Ext: ext, Ref: ref}
+}
+
 // codes contains a map of error codes to metadata.
 var codes = map[int]Coder{}
 var codeMux = &sync.Mutex{}
@@ -109,9 +115,25 @@ func ParseCoder(err error) Coder {
 	}
 
 	if v, ok := err.(*withCode); ok {
-		if coder, ok := codes[v.code]; ok {
-			return coder
-		}
+		return v.lookupCoder()
+	}
+
+	return unknownCoder
+}
+
+// HasCode reports whether err is created by WithCode, WrapC or WrapCoder.
+func HasCode(err error) bool {
+	_, ok := err.(*withCode)
+	return ok
+}
+
+// lookupCoder returns the registered coder of the code, or the coder carried by the error.
+func (w *withCode) lookupCoder() Coder {
+	if coder, ok := codes[w.code]; ok {
+		return coder
+	}
+	if w.coder != nil {
+		return w.coder
 	}
 
 	return unknownCoder
@@ -152,6 +152,7 @@ func WithStack(err error) error {
 			err:   e.err,
 			code:  e.code,
 			cause: err,
+			coder: e.coder,
 			stack: callers(),
 		}
 	}
@@ -206,6 +207,7 @@ func Wrap(err error, message string) error {
 			err:   fmt.Errorf(message),
 			code:  e.code,
 			cause: err,
+			coder: e.coder,
 			stack: callers(),
 		}
 	}
@@ -233,6 +235,7 @@ func Wrapf(err error, format string, args ...interface{}) error {
 			err:   fmt.Errorf(format, args...),
 			code:  e.code,
 			cause: err,
+			coder: e.coder,
 			stack: callers(),
 		}
 	}
@@ -300,6 +303,8 @@ type withCode struct {
 	err   error
 	code  int
 	cause error
+	// coder is used when the code is not registered, e.g. the error of a remote service.
+	coder Coder
 	*stack
 }
 
@@ -311,6 +316,22 @@ func WithCode(code int, format string, args ...interface{}) error {
 	}
 }
 
+// WrapCoder wraps err with the code of coder, ParseCoder returns coder if its code is not registered.
+// It is used to rebuild the errors of remote services.
+func WrapCoder(err error, coder Coder, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	return &withCode{
+		err:   fmt.Errorf(format, args...),
+		code:  coder.Code(),
+		cause: err,
+		coder: coder,
+		stack: callers(),
+	}
+}
+
 func WrapC(err error, code int, format string, args ...interface{}) error {
 	if err == nil {
 		return nil
@@ -168,10 +168,7 @@ func buildFormatInfo(e error) *formatInfo {
 			stack:   err.stack,
 		}
 	case *withCode:
-		coder, ok := codes[err.code]
-		if !ok {
-			coder = unknownCoder
-		}
+		coder := err.lookupCoder()
 
 		extMsg := coder.String()
 		if extMsg == "" {